@@ -1,23 +1,114 @@
 package main
 
 import (
-	"encoding/xml"
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 
-	"github.com/rsookram/axmlfmt/internal/parse"
+	"github.com/rsookram/axmlfmt/format"
+	"github.com/rsookram/axmlfmt/internal/codestyle"
+	"github.com/rsookram/axmlfmt/internal/config"
+	"github.com/rsookram/axmlfmt/internal/diff"
+	"github.com/rsookram/axmlfmt/internal/editorconfig"
+	"github.com/rsookram/axmlfmt/internal/files"
 	"github.com/rsookram/axmlfmt/internal/printer"
 )
 
-const indent = "    "
+const stdinName = "<standard input>"
+
+// Exit codes
+const (
+	exitOpen        = 1
+	exitParse       = 2
+	exitWrite       = 3
+	exitUnformatted = 4
+)
 
 var Version = "development"
 
 var version = flag.Bool("V", false, "print version information")
 var write = flag.Bool("w", false, "write result to (source) file instead of stdout")
+var list = flag.Bool("l", false, "list files whose formatting differs from axmlfmt's")
+var showDiff = flag.Bool("d", false, "display diffs instead of rewriting files")
+var check = flag.Bool("check", false, "exit with status 4 if any file isn't formatted, without printing the result")
+var jobs = flag.Int("j", runtime.GOMAXPROCS(0), "number of files to format in parallel")
+var lossless = flag.Bool("lossless", false, "keep character data and attribute values as they're written in the source")
+var stdinFilepath = flag.String("stdin-filepath", "", "path to use for standard input in messages and settings")
+
+var configs = config.NewLoader()
+var editorConfigs = editorconfig.NewLoader()
+
+var include stringList
+var exclude stringList
+
+// Flags which determine the style, which export-idea-style takes as well
+var indent = new(string)
+var attrIndent = new(string)
+var blankLines = new(string)
+var maxBlankLines = new(string)
+var attrOrder = new(string)
+var attrPreset = new(string)
+var codeStyle = new(string)
+
+func init() {
+	flag.Var(&include, "include", "glob of files to format in directories (default \""+files.DefaultInclude+"\", repeatable)")
+	flag.Var(&exclude, "exclude", "glob of files and directories to skip in directories (repeatable)")
+	defineStyleFlags(flag.CommandLine)
+}
+
+// defineStyleFlags defines the flags which determine the style in fs
+func defineStyleFlags(fs *flag.FlagSet) {
+	fs.StringVar(indent, "indent", "", "indent for each level of nesting, as a number of spaces or \"tab\"")
+	fs.StringVar(attrIndent, "attr-indent", "", "indent of attributes relative to their element, as a number of spaces, \"tab\" or a number of tabs like \"2tabs\"")
+	fs.StringVar(blankLines, "blank-lines", "", "where to print blank lines between elements, \"always\", \"never\" or \"preserve\"")
+	fs.StringVar(maxBlankLines, "max-blank-lines", "", "largest number of consecutive blank lines kept with -blank-lines=preserve (default 1)")
+	fs.StringVar(attrOrder, "attr-order", "", "comma-separated rules which attributes are ordered by, like \"xmlns:*,style,android:id,android:*,*:*\"")
+	fs.StringVar(attrPreset, "attr-preset", "", "built-in order of attributes, \"axmlfmt\" or \"android-studio\"")
+	fs.StringVar(codeStyle, "code-style", "", "path of an IntelliJ or Android Studio code style file, like .idea/codeStyles/Project.xml, to take XML settings from")
+}
+
+// stringList is a flag which can be given multiple times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// result is the outcome of formatting a single file
+type result struct {
+	// stdout is what's printed to stdout for the file
+	stdout []byte
+	// formatted is the formatted document
+	formatted []byte
+	// changed is whether the formatted document differs from the input
+	changed bool
+	err     error
+	// exitCode is the status to exit with when err is non-nil
+	exitCode int
+}
+
+// exportIdeaStyle is the subcommand which writes an Android Studio code style
+// matching the formatting options
+const exportIdeaStyle = "export-idea-style"
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == exportIdeaStyle {
+		os.Exit(exportStyle(os.Args[2:]))
+	}
+
 	flag.Parse()
 
 	if *version {
@@ -25,42 +116,449 @@ func main() {
 		return
 	}
 
-	filenames := flag.Args()
+	if *jobs < 1 {
+		fmt.Fprintf(os.Stderr, "-j must be at least 1\n")
+		os.Exit(exitOpen)
+	}
+
+	err := parseFlagOptions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(exitOpen)
+	}
+
+	if flag.NArg() == 0 {
+		if *write {
+			fmt.Fprintf(os.Stderr, "cannot use -w with standard input\n")
+			os.Exit(exitOpen)
+		}
+
+		name := *stdinFilepath
+		if name == "" {
+			name = stdinName
+		}
+
+		results := make(chan result, 1)
+		results <- processStdin(name, *stdinFilepath)
+		os.Exit(report([]chan result{results}))
+	}
+
+	filenames, err := findFiles(flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(exitOpen)
+	}
+
+	os.Exit(report(formatAll(filenames, *jobs)))
+}
+
+// exportStyle writes the code style for the options which apply to XML files
+// in the current directory to the file given in args, or to stdout when
+// there's none, and returns the status to exit with. Only the flags which
+// determine the style can be given.
+func exportStyle(args []string) int {
+	fs := flag.NewFlagSet(exportIdeaStyle, flag.ExitOnError)
+	defineStyleFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: axmlfmt %s [flags] [file]\n", exportIdeaStyle)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() > 1 {
+		fmt.Fprintf(os.Stderr, "%s takes at most one file\n", exportIdeaStyle)
+		return exitOpen
+	}
+
+	err := parseFlagOptions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return exitOpen
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return exitOpen
+	}
+
+	c, err := configs.ForDir(wd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return exitOpen
+	}
+
+	props, err := editorConfigs.XMLProperties(wd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return exitOpen
+	}
+
+	opts := resolveOptions(props, c)
 
-	for _, name := range filenames {
-		r, err := os.Open(name)
+	out := &bytes.Buffer{}
+	err = codestyle.Write(out, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return exitWrite
+	}
+
+	if fs.NArg() == 0 {
+		_, err = os.Stdout.Write(out.Bytes())
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-			os.Exit(1)
+			return exitWrite
 		}
+		return 0
+	}
 
-		decoder := xml.NewDecoder(r)
+	name := fs.Arg(0)
+	err = writeFile(name, out.Bytes())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write %v: %v\n", name, err)
+		return exitWrite
+	}
+
+	return 0
+}
 
-		elements, err := parse.ReadXML(decoder)
+// writeFile replaces the contents of the file, or creates it along with its
+// directory when it doesn't exist
+func writeFile(name string, data []byte) error {
+	_, err := os.Stat(name)
+	if err == nil {
+		return files.WriteAtomic(name, data)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(name), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(name, data, 0644)
+}
+
+// flagOptions holds the options given as flags, which take precedence over
+// settings from files
+var flagOptions struct {
+	indent        string
+	attrIndent    string
+	blankLines    *printer.BlankLinePolicy
+	maxBlankLines *int
+	attrOrder     []printer.AttrRule
+	codeStyle     *codestyle.Style
+}
+
+func parseFlagOptions() error {
+	var err error
+
+	if *codeStyle != "" {
+		flagOptions.codeStyle, err = codestyle.Load(*codeStyle)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-			os.Exit(2)
+			return fmt.Errorf("-code-style: %v", err)
 		}
+	}
 
-		p := printer.New(indent)
-		err = writeOutput(p, elements, *write, name)
+	if *indent != "" {
+		flagOptions.indent, err = printer.ParseIndent(*indent)
+		if err != nil {
+			return fmt.Errorf("-indent: %v", err)
+		}
+	}
+
+	if *attrIndent != "" {
+		flagOptions.attrIndent, err = printer.ParseIndent(*attrIndent)
+		if err != nil {
+			return fmt.Errorf("-attr-indent: %v", err)
+		}
+	}
+
+	if *blankLines != "" {
+		policy, err := printer.ParseBlankLinePolicy(*blankLines)
+		if err != nil {
+			return fmt.Errorf("-blank-lines: %v", err)
+		}
+		flagOptions.blankLines = &policy
+	}
+
+	if *maxBlankLines != "" {
+		n, err := strconv.Atoi(*maxBlankLines)
+		if err != nil || n < 0 {
+			return fmt.Errorf("-max-blank-lines: invalid value %q, expected a number which isn't negative", *maxBlankLines)
+		}
+		flagOptions.maxBlankLines = &n
+	}
+
+	if *attrPreset != "" {
+		if *attrOrder != "" {
+			return errors.New("-attr-preset can't be combined with -attr-order")
+		}
+
+		flagOptions.attrOrder, err = printer.AttrOrderPreset(*attrPreset)
+		if err != nil {
+			return fmt.Errorf("-attr-preset: %v", err)
+		}
+	}
+
+	if *attrOrder != "" {
+		flagOptions.attrOrder, err = printer.ParseAttrRules(printer.SplitAttrRules(*attrOrder), nil)
+		if err != nil {
+			return fmt.Errorf("-attr-order: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// applyFlagOptions overrides the given options with those given as flags
+func applyFlagOptions(opts *format.Options) {
+	if flagOptions.codeStyle != nil {
+		flagOptions.codeStyle.Apply(opts)
+	}
+
+	if flagOptions.indent != "" {
+		opts.Indent = flagOptions.indent
+	}
+
+	if flagOptions.attrIndent != "" {
+		opts.AttributeIndent = flagOptions.attrIndent
+	}
+
+	if flagOptions.blankLines != nil {
+		opts.BlankLines = *flagOptions.blankLines
+	}
+
+	if flagOptions.maxBlankLines != nil {
+		opts.MaxBlankLines = *flagOptions.maxBlankLines
+	}
+
+	if flagOptions.attrOrder != nil {
+		opts.AttributeOrder = flagOptions.attrOrder
+	}
+
+	if *lossless {
+		opts.Lossless = true
+	}
+}
+
+// formatAll formats the given files using n goroutines. The result of each
+// file is sent on the channel at the same position as its name.
+func formatAll(filenames []string, n int) []chan result {
+	results := make([]chan result, len(filenames))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+
+	indices := make(chan int)
+	go func() {
+		for i := range filenames {
+			indices <- i
+		}
+		close(indices)
+	}()
+
+	for w := 0; w < n; w++ {
+		go func() {
+			for i := range indices {
+				results[i] <- processFile(filenames[i])
+			}
+		}()
+	}
+
+	return results
+}
+
+// report prints the results in order as they become available and returns
+// the status to exit with. Errors are reported for each file, and the status
+// is determined by the first file which failed.
+func report(results []chan result) int {
+	exitCode := 0
+	unformatted := false
+
+	for _, c := range results {
+		r := <-c
+
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", r.err.Error())
+			if exitCode == 0 {
+				exitCode = r.exitCode
+			}
+			continue
+		}
+
+		_, err := os.Stdout.Write(r.stdout)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-			os.Exit(3)
+			return exitWrite
+		}
+
+		unformatted = unformatted || r.changed
+	}
+
+	if exitCode == 0 && unformatted && (*list || *check) {
+		exitCode = exitUnformatted
+	}
+
+	return exitCode
+}
+
+// findFiles returns the files to format for the given command line arguments.
+// Directories are searched recursively for XML files. Arguments which can't
+// be accessed are returned as-is so that the error is reported when they're
+// formatted.
+func findFiles(args []string) ([]string, error) {
+	filenames := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil || !info.IsDir() {
+			filenames = append(filenames, arg)
+			continue
+		}
+
+		found, err := files.Find(arg, include, exclude)
+		if err != nil {
+			return nil, err
 		}
+		filenames = append(filenames, found...)
 	}
+
+	return filenames, nil
 }
 
-func writeOutput(p printer.Printer, elements []parse.Element, write bool, inputFileName string) error {
-	if !write {
-		return p.Fprint(os.Stdout, elements)
+// processStdin formats the document from stdin, using the settings for the
+// file at path. When path is empty, the settings for the working directory
+// are used.
+func processStdin(name, path string) result {
+	src, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return result{err: err, exitCode: exitOpen}
+	}
+
+	opts, excluded, err := optionsFor(path)
+	if err != nil {
+		return result{err: err, exitCode: exitOpen}
 	}
+	if excluded {
+		// Pass the document through unchanged so that editors don't lose it
+		return process(name, src, nil)
+	}
+
+	return process(name, src, &opts)
+}
 
-	f, err := os.Create(inputFileName)
+func processFile(name string) result {
+	opts, excluded, err := optionsFor(name)
 	if err != nil {
-		return fmt.Errorf("failed to create %v: %v", inputFileName, err)
+		return result{err: err, exitCode: exitOpen}
+	}
+	if excluded {
+		return result{}
+	}
+
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return result{err: err, exitCode: exitOpen}
+	}
+
+	r := process(name, src, &opts)
+	if r.err != nil || !*write || !r.changed {
+		return r
+	}
+
+	err = files.WriteAtomic(name, r.formatted)
+	if err != nil {
+		return result{err: fmt.Errorf("failed to write %v: %v", name, err), exitCode: exitWrite}
+	}
+
+	return r
+}
+
+// optionsFor returns the options to format the file at path with, and
+// whether the file is excluded from formatting. When path is empty, they're
+// the options for standard input in the current directory.
+func optionsFor(path string) (format.Options, bool, error) {
+	if path == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return format.Options{}, false, err
+		}
+
+		c, err := configs.ForDir(wd)
+		if err != nil {
+			return format.Options{}, false, err
+		}
+
+		return resolveOptions(nil, c), false, nil
+	}
+
+	c, err := configs.ForFile(path)
+	if err != nil {
+		return format.Options{}, false, err
+	}
+
+	if c != nil && c.Excludes(path) {
+		return format.Options{}, true, nil
+	}
+
+	props, err := editorConfigs.Properties(path)
+	if err != nil {
+		return format.Options{}, false, err
+	}
+
+	return resolveOptions(props, c), false, nil
+}
+
+// resolveOptions returns the options from the .editorconfig properties and
+// the configuration, which is nil when there isn't a configuration file.
+// Flags take precedence over settings in .axmlfmt.toml, which take
+// precedence over those in .editorconfig.
+func resolveOptions(props editorconfig.Properties, c *config.Config) format.Options {
+	opts := format.DefaultOptions()
+
+	props.Apply(&opts)
+
+	if c != nil {
+		c.Apply(&opts)
+	}
+
+	applyFlagOptions(&opts)
+	return opts
+}
+
+// process formats src and determines what to print for it according to the
+// output flags. When opts is nil, src is left as-is.
+func process(name string, src []byte, opts *format.Options) result {
+	out := src
+	if opts != nil {
+		var err error
+		out, err = format.Source(src, *opts)
+		if ferr, ok := err.(*format.Error); ok {
+			ferr.Filename = name
+			return result{err: ferr, exitCode: exitParse}
+		}
+		if err != nil {
+			return result{err: fmt.Errorf("%s: %v", name, err), exitCode: exitParse}
+		}
+	}
+
+	changed := !bytes.Equal(src, out)
+
+	stdout := &bytes.Buffer{}
+
+	if *list && changed {
+		fmt.Fprintln(stdout, name)
+	}
+
+	if *showDiff && changed {
+		stdout.Write(diff.Unified(name+".orig", name, src, out))
+	}
+
+	if !*write && !*list && !*showDiff && !*check {
+		stdout.Write(out)
 	}
-	defer f.Close()
 
-	return p.Fprint(f, elements)
+	return result{stdout: stdout.Bytes(), formatted: out, changed: changed}
 }
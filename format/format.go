@@ -0,0 +1,97 @@
+// Package format formats Android XML resources in the same way as the
+// axmlfmt command.
+package format
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/rsookram/axmlfmt/internal/parse"
+	"github.com/rsookram/axmlfmt/internal/printer"
+)
+
+// Options configures how documents are formatted. Use DefaultOptions to get
+// the options which axmlfmt uses when there's no configuration, rather than
+// the zero value.
+type Options = printer.Options
+
+// BlankLinePolicy determines where blank lines are printed between elements
+type BlankLinePolicy = printer.BlankLinePolicy
+
+const (
+	// BlankLinesAlways prints a blank line before each element and comment
+	// which follows an element
+	BlankLinesAlways = printer.BlankLinesAlways
+	// BlankLinesNever doesn't print any blank lines
+	BlankLinesNever = printer.BlankLinesNever
+	// BlankLinesPreserve keeps the blank lines before elements and comments
+	// from the source, up to Options.MaxBlankLines of them
+	BlankLinesPreserve = printer.BlankLinesPreserve
+)
+
+// AttrRule matches attributes by namespace and local name. Attributes are
+// ordered by the first rule in Options.AttributeOrder which matches them, and
+// alphabetically by local name when the same rule matches.
+type AttrRule = printer.AttrRule
+
+// ParseAttrRule returns the rule described by s, which is written as
+// `prefix:name`, or just `name` for attributes without a namespace. The
+// prefix is "xmlns" for namespace declarations, "*" for any namespace, or a
+// prefix from namespaces, which maps prefixes to URIs, or one of the
+// well-known prefixes such as android, app and tools. The name is a glob, or
+// a regular expression between slashes.
+func ParseAttrRule(s string, namespaces map[string]string) (AttrRule, error) {
+	return printer.ParseAttrRule(s, namespaces)
+}
+
+// AttrOrderPreset returns the rules of the built-in attribute order with the
+// given name, for use as Options.AttributeOrder. "axmlfmt" is the default
+// order and "android-studio" is the order of Android Studio's default
+// arrangement for Android XML.
+func AttrOrderPreset(name string) ([]AttrRule, error) {
+	return printer.AttrOrderPreset(name)
+}
+
+// Error is returned for documents which can't be parsed. It holds the
+// position of the problem.
+type Error = parse.Error
+
+// DefaultOptions returns the options used by axmlfmt when there's no
+// configuration
+func DefaultOptions() Options {
+	return printer.DefaultOptions()
+}
+
+// Source formats the document in src and returns the result. When src isn't
+// well-formed, the returned error is an *Error.
+func Source(src []byte, opts Options) ([]byte, error) {
+	root, err := parse.ReadXML(src)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &bytes.Buffer{}
+	err = printer.New(opts).Fprint(w, root)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.Bytes(), nil
+}
+
+// Reader formats the document read from r and writes the result to w.
+// Nothing is written when the document can't be formatted.
+func Reader(r io.Reader, w io.Writer, opts Options) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	out, err := Source(src, opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(out)
+	return err
+}
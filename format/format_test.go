@@ -0,0 +1,85 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const unformatted = `<LinearLayout android:orientation="vertical" xmlns:android="http://schemas.android.com/apk/res/android">
+<TextView android:text="@string/hello" android:id="@+id/text"/></LinearLayout>`
+
+func TestSource(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Indent = "  "
+
+	out, err := Source([]byte(unformatted), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<LinearLayout
+  xmlns:android="http://schemas.android.com/apk/res/android"
+  android:orientation="vertical">
+
+  <TextView
+    android:id="@+id/text"
+    android:text="@string/hello" />
+</LinearLayout>
+`
+	if string(out) != expected {
+		t.Errorf("got: %s, want %s", out, expected)
+	}
+}
+
+func TestReader(t *testing.T) {
+	w := &bytes.Buffer{}
+	err := Reader(strings.NewReader(unformatted), w, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := Source([]byte(unformatted), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(w.Bytes(), expected) {
+		t.Errorf("got: %s, want %s", w.Bytes(), expected)
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	w := &bytes.Buffer{}
+	err := Reader(strings.NewReader("<resources>\n<string>"), w, DefaultOptions())
+
+	ferr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("got %#v, want *Error", err)
+	}
+	if ferr.Line != 2 {
+		t.Errorf("got line %d, want 2", ferr.Line)
+	}
+
+	if w.Len() != 0 {
+		t.Errorf("got %q, want nothing written", w.String())
+	}
+}
+
+func ExampleSource() {
+	src := []byte(`<resources><string name="app_name">Example</string></resources>`)
+
+	out, err := Source(src, DefaultOptions())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Print(string(out))
+	// Output:
+	// <resources>
+	//
+	//     <string name="app_name">Example</string>
+	// </resources>
+}
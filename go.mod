@@ -1,3 +1,5 @@
 module github.com/rsookram/axmlfmt
 
 go 1.17
+
+require github.com/BurntSushi/toml v1.3.2
@@ -0,0 +1,362 @@
+// Package codestyle reads the XML settings of IntelliJ and Android Studio
+// code style files, such as .idea/codeStyles/Project.xml, and maps them to
+// printer options.
+package codestyle
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rsookram/axmlfmt/internal/parse"
+	"github.com/rsookram/axmlfmt/internal/printer"
+)
+
+// anyPrefix is an optional prefix in the name pattern of an arrangement rule
+const anyPrefix = "(.*:)?"
+
+// Style holds the settings of a code style which axmlfmt uses
+type Style struct {
+	// Indent is printed once for each level of nesting. It's empty when the
+	// code style doesn't have indent options for XML.
+	Indent string
+
+	// AttributeIndent is the continuation indent, which is printed after the
+	// indent of an element before each of its attributes
+	AttributeIndent string
+
+	// AttributeOrder is the order of attributes from the arrangement rules,
+	// or nil when there are none
+	AttributeOrder []printer.AttrRule
+}
+
+// Load reads the code style file at path
+func Load(path string) (*Style, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := parse.ReadXML(src)
+	if perr, ok := err.(*parse.Error); ok {
+		perr.Filename = path
+		return nil, perr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	s := &Style{}
+
+	settings := xmlSettings(root)
+	if settings == nil {
+		return s, nil
+	}
+
+	if indentOptions := child(settings, "indentOptions"); indentOptions != nil {
+		s.Indent, s.AttributeIndent, err = indents(options(indentOptions))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	if rules := child(child(settings, "arrangement"), "rules"); rules != nil {
+		s.AttributeOrder, err = attributeOrder(rules, src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	return s, nil
+}
+
+// Apply overrides the given options with the settings from the code style
+func (s *Style) Apply(opts *printer.Options) {
+	if s.Indent != "" {
+		opts.Indent = s.Indent
+		opts.AttributeIndent = s.AttributeIndent
+	}
+
+	if s.AttributeOrder != nil {
+		opts.AttributeOrder = s.AttributeOrder
+	}
+}
+
+// xmlSettings returns the `<codeStyleSettings language="XML">` element in
+// the tree, or nil if there isn't one
+func xmlSettings(n *parse.Node) *parse.Node {
+	for _, c := range n.Children {
+		if c.Type != parse.ElementNode {
+			continue
+		}
+
+		if c.Name.Local == "codeStyleSettings" && attr(c, "language") == "XML" {
+			return c
+		}
+
+		if found := xmlSettings(c); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// indents returns the indent and continuation indent described by the
+// options of an `<indentOptions>` element. Options which are missing have
+// IntelliJ's default value.
+func indents(opts map[string]string) (string, string, error) {
+	size, err := intOption(opts, "INDENT_SIZE", 4)
+	if err != nil {
+		return "", "", err
+	}
+	continuation, err := intOption(opts, "CONTINUATION_INDENT_SIZE", 8)
+	if err != nil {
+		return "", "", err
+	}
+	tabSize, err := intOption(opts, "TAB_SIZE", 4)
+	if err != nil {
+		return "", "", err
+	}
+
+	if opts["USE_TAB_CHARACTER"] == "true" {
+		if tabSize == 0 {
+			tabSize = 4
+		}
+		return tabs(size, tabSize), tabs(continuation, tabSize), nil
+	}
+
+	return strings.Repeat(" ", size), strings.Repeat(" ", continuation), nil
+}
+
+// tabs returns the tabs which make up an indent of the given number of
+// columns, rounded up to a whole tab
+func tabs(columns, tabSize int) string {
+	return strings.Repeat("\t", (columns+tabSize-1)/tabSize)
+}
+
+func intOption(opts map[string]string, name string, def int) (int, error) {
+	value, ok := opts[name]
+	if !ok {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q, expected a number of columns", name, value)
+	}
+
+	return n, nil
+}
+
+// attributeOrder returns the order of attributes from the rules of an
+// arrangement, in the order they appear. Rules for tags are skipped, and
+// rules with ANDROID_ATTRIBUTE_ORDER are expanded into the rules of that
+// order. src is the source of the code style, which errors refer to.
+func attributeOrder(rules *parse.Node, src []byte) ([]printer.AttrRule, error) {
+	order := make([]printer.AttrRule, 0)
+
+	for _, rule := range descendants(rules, "rule") {
+		match := child(rule, "match")
+		if match == nil || len(descendants(match, "XML_TAG")) > 0 {
+			continue
+		}
+		if len(descendants(match, "OR")) > 0 {
+			return nil, fmt.Errorf("unsupported arrangement rule on line %d: rules with OR can't be used", line(src, rule.Start))
+		}
+
+		name := text(first(descendants(match, "NAME")))
+		ns, hasNS := ".*", false
+		if n := first(descendants(match, "XML_NAMESPACE")); n != nil {
+			ns, hasNS = text(n), true
+		}
+
+		r, err := attrRule(name, ns, hasNS)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported arrangement rule on line %d: %v", line(src, rule.Start), err)
+		}
+
+		if text(child(rule, "order")) == "ANDROID_ATTRIBUTE_ORDER" {
+			if r.Name.String() != "^(?:.*)$" {
+				return nil, fmt.Errorf("unsupported arrangement rule on line %d: ANDROID_ATTRIBUTE_ORDER can only be used for rules which match any name", line(src, rule.Start))
+			}
+
+			order = append(order, printer.AndroidAttributeOrder(r.Namespace)...)
+			continue
+		}
+
+		order = append(order, r)
+	}
+
+	return order, nil
+}
+
+// attrRule returns the rule which matches the same attributes as an
+// arrangement rule with the given patterns. In arrangement rules, the name
+// pattern matches the name with its prefix and the namespace pattern matches
+// the namespace URI.
+func attrRule(name, ns string, hasNS bool) (printer.AttrRule, error) {
+	if name == "" {
+		name = ".*"
+	}
+
+	prefix, local := "", name
+	if strings.HasPrefix(name, anyPrefix) {
+		// An optional prefix, which matches attributes in any namespace
+		prefix, local = ".*", name[len(anyPrefix):]
+	} else if i := strings.Index(name, ":"); i >= 0 {
+		prefix, local = name[:i], name[i+1:]
+	}
+
+	var namespace string
+	switch {
+	case hasNS && (ns == "^$" || ns == ""):
+		namespace = ""
+		if prefix == "xmlns" {
+			namespace = "xmlns"
+		} else {
+			local = name
+		}
+	case ns == ".*":
+		switch {
+		case prefix == "" && strings.HasPrefix(name, ".*"):
+			namespace = printer.AnyNamespace
+		case prefix == "":
+			namespace = ""
+		case prefix == ".*":
+			namespace = printer.AnyNamespace
+		case prefix == "xmlns":
+			namespace = "xmlns"
+		default:
+			uri, ok := printer.WellKnownNamespace(prefix)
+			if !ok {
+				return printer.AttrRule{}, fmt.Errorf("unknown namespace prefix %q", prefix)
+			}
+			namespace = uri
+		}
+	default:
+		uri, ok := literal(ns)
+		if !ok {
+			return printer.AttrRule{}, fmt.Errorf("namespace pattern %q isn't a URI", ns)
+		}
+		namespace = uri
+	}
+
+	re, err := regexp.Compile("^(?:" + local + ")$")
+	if err != nil {
+		return printer.AttrRule{}, err
+	}
+
+	return printer.AttrRule{Namespace: namespace, Name: re}, nil
+}
+
+// literal returns the text matched by a pattern which only matches one URI,
+// where unescaped dots are taken to be literal dots
+func literal(pattern string) (string, bool) {
+	pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "^"), "$")
+
+	b := &strings.Builder{}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteByte(pattern[i])
+		case strings.IndexByte(`*+?()[]{}|^$\`, c) >= 0:
+			return "", false
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String(), b.Len() > 0
+}
+
+// options returns the values of the `<option name="..." value="..." />`
+// children of n by name
+func options(n *parse.Node) map[string]string {
+	opts := make(map[string]string)
+	for _, c := range n.Children {
+		if c.Type == parse.ElementNode && c.Name.Local == "option" {
+			opts[attr(c, "name")] = attr(c, "value")
+		}
+	}
+
+	return opts
+}
+
+// child returns the first child element of n with the given name, or nil if
+// there isn't one or n is nil
+func child(n *parse.Node, name string) *parse.Node {
+	if n == nil {
+		return nil
+	}
+
+	for _, c := range n.Children {
+		if c.Type == parse.ElementNode && c.Name.Local == name {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// descendants returns the elements below n with the given name in document
+// order
+func descendants(n *parse.Node, name string) []*parse.Node {
+	found := make([]*parse.Node, 0)
+	for _, c := range n.Children {
+		if c.Type != parse.ElementNode {
+			continue
+		}
+
+		if c.Name.Local == name {
+			found = append(found, c)
+		}
+		found = append(found, descendants(c, name)...)
+	}
+
+	return found
+}
+
+func first(nodes []*parse.Node) *parse.Node {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	return nodes[0]
+}
+
+// text returns the text content of n, or "" if n is nil
+func text(n *parse.Node) string {
+	if n == nil {
+		return ""
+	}
+
+	b := &strings.Builder{}
+	for _, c := range n.Children {
+		if c.Type == parse.TextNode {
+			b.WriteString(c.Data)
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// line returns the line number of the given offset in src
+func line(src []byte, offset int64) int {
+	return bytes.Count(src[:offset], []byte("\n")) + 1
+}
+
+func attr(n *parse.Node, name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,435 @@
+package codestyle
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rsookram/axmlfmt/internal/parse"
+	"github.com/rsookram/axmlfmt/internal/printer"
+)
+
+const project = `<component name="ProjectCodeStyleConfiguration">
+  <code_scheme name="Project" version="173">
+    <codeStyleSettings language="JAVA">
+      <indentOptions>
+        <option name="INDENT_SIZE" value="2" />
+      </indentOptions>
+    </codeStyleSettings>
+    <codeStyleSettings language="XML">
+      <indentOptions>
+        <option name="CONTINUATION_INDENT_SIZE" value="4" />
+      </indentOptions>
+      <arrangement>
+        <rules>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>xmlns:android</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>^$</XML_NAMESPACE>
+                </AND>
+              </match>
+            </rule>
+          </section>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>xmlns:.*</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>^$</XML_NAMESPACE>
+                </AND>
+              </match>
+              <order>BY_NAME</order>
+            </rule>
+          </section>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>.*:id</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>http://schemas.android.com/apk/res/android</XML_NAMESPACE>
+                </AND>
+              </match>
+            </rule>
+          </section>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>style</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>^$</XML_NAMESPACE>
+                </AND>
+              </match>
+            </rule>
+          </section>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>.*:layout_.*</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>http://schemas.android.com/apk/res/android</XML_NAMESPACE>
+                </AND>
+              </match>
+              <order>BY_NAME</order>
+            </rule>
+          </section>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>.*</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>.*</XML_NAMESPACE>
+                </AND>
+              </match>
+              <order>BY_NAME</order>
+            </rule>
+          </section>
+        </rules>
+      </arrangement>
+    </codeStyleSettings>
+  </code_scheme>
+</component>
+`
+
+// androidStudioProject is the code style of a new Android Studio project
+const androidStudioProject = `<component name="ProjectCodeStyleConfiguration">
+  <code_scheme name="Project" version="173">
+    <JetCodeStyleSettings>
+      <option name="CODE_STYLE_DEFAULTS" value="KOTLIN_OFFICIAL" />
+    </JetCodeStyleSettings>
+    <codeStyleSettings language="XML">
+      <option name="FORCE_REARRANGE_MODE" value="1" />
+      <indentOptions>
+        <option name="CONTINUATION_INDENT_SIZE" value="4" />
+      </indentOptions>
+      <arrangement>
+        <rules>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>xmlns:android</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>^$</XML_NAMESPACE>
+                </AND>
+              </match>
+            </rule>
+          </section>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>xmlns:.*</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>^$</XML_NAMESPACE>
+                </AND>
+              </match>
+              <order>BY_NAME</order>
+            </rule>
+          </section>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>.*:id</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>http://schemas.android.com/apk/res/android</XML_NAMESPACE>
+                </AND>
+              </match>
+            </rule>
+          </section>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>.*:name</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>http://schemas.android.com/apk/res/android</XML_NAMESPACE>
+                </AND>
+              </match>
+            </rule>
+          </section>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>name</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>^$</XML_NAMESPACE>
+                </AND>
+              </match>
+            </rule>
+          </section>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>style</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>^$</XML_NAMESPACE>
+                </AND>
+              </match>
+            </rule>
+          </section>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>.*</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>^$</XML_NAMESPACE>
+                </AND>
+              </match>
+              <order>BY_NAME</order>
+            </rule>
+          </section>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>.*</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>http://schemas.android.com/apk/res/android</XML_NAMESPACE>
+                </AND>
+              </match>
+              <order>ANDROID_ATTRIBUTE_ORDER</order>
+            </rule>
+          </section>
+          <section>
+            <rule>
+              <match>
+                <AND>
+                  <NAME>.*</NAME>
+                  <XML_ATTRIBUTE />
+                  <XML_NAMESPACE>.*</XML_NAMESPACE>
+                </AND>
+              </match>
+              <order>BY_NAME</order>
+            </rule>
+          </section>
+        </rules>
+      </arrangement>
+    </codeStyleSettings>
+    <codeStyleSettings language="kotlin">
+      <option name="CODE_STYLE_DEFAULTS" value="KOTLIN_OFFICIAL" />
+    </codeStyleSettings>
+  </code_scheme>
+</component>
+`
+
+func TestLoad(t *testing.T) {
+	path := writeStyle(t, project)
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Indent != "    " || s.AttributeIndent != "    " {
+		t.Errorf("got indents %q and %q, want 4 spaces", s.Indent, s.AttributeIndent)
+	}
+
+	opts := printer.DefaultOptions()
+	opts.Indent = "  "
+	s.Apply(&opts)
+
+	src := `<FrameLayout xmlns:tools="http://schemas.android.com/tools" android:padding="4dp" style="@style/card" android:layout_width="match_parent" xmlns:android="http://schemas.android.com/apk/res/android" tools:ignore="Overdraw" android:id="@+id/card" />`
+	out, err := formatSource([]byte(src), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<FrameLayout
+    xmlns:android="http://schemas.android.com/apk/res/android"
+    xmlns:tools="http://schemas.android.com/tools"
+    android:id="@+id/card"
+    style="@style/card"
+    android:layout_width="match_parent"
+    android:padding="4dp"
+    tools:ignore="Overdraw" />
+`
+	if string(out) != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestLoadAndroidStudioDefault(t *testing.T) {
+	path := writeStyle(t, androidStudioProject)
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := printer.DefaultOptions()
+	s.Apply(&opts)
+
+	src := `<TextView android:textSize="12sp" android:height="8dp" xmlns:tools="http://schemas.android.com/tools" android:layout_height="wrap_content" style="@style/label" android:layout_marginTop="4dp" tools:text="x" android:layout_width="match_parent" xmlns:android="http://schemas.android.com/apk/res/android" android:width="8dp" android:id="@+id/label" />`
+	out, err := formatSource([]byte(src), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<TextView
+    xmlns:android="http://schemas.android.com/apk/res/android"
+    xmlns:tools="http://schemas.android.com/tools"
+    android:id="@+id/label"
+    style="@style/label"
+    android:layout_width="match_parent"
+    android:layout_height="wrap_content"
+    android:layout_marginTop="4dp"
+    android:width="8dp"
+    android:height="8dp"
+    android:textSize="12sp"
+    tools:text="x" />
+`
+	if string(out) != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestLoadTabs(t *testing.T) {
+	path := writeStyle(t, `<component name="ProjectCodeStyleConfiguration">
+  <code_scheme name="Project">
+    <codeStyleSettings language="XML">
+      <indentOptions>
+        <option name="USE_TAB_CHARACTER" value="true" />
+      </indentOptions>
+    </codeStyleSettings>
+  </code_scheme>
+</component>`)
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The continuation indent defaults to 8 columns, which is two tabs
+	if s.Indent != "\t" || s.AttributeIndent != "\t\t" {
+		t.Errorf("got indents %q and %q, want one and two tabs", s.Indent, s.AttributeIndent)
+	}
+	if s.AttributeOrder != nil {
+		t.Errorf("got %v, want no attribute order", s.AttributeOrder)
+	}
+}
+
+func TestLoadTabSize(t *testing.T) {
+	path := writeStyle(t, `<codeStyleSettings language="XML">
+  <indentOptions>
+    <option name="INDENT_SIZE" value="8" />
+    <option name="CONTINUATION_INDENT_SIZE" value="4" />
+    <option name="TAB_SIZE" value="4" />
+    <option name="USE_TAB_CHARACTER" value="true" />
+  </indentOptions>
+</codeStyleSettings>`)
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Indent != "\t\t" || s.AttributeIndent != "\t" {
+		t.Errorf("got indents %q and %q, want two tabs and one", s.Indent, s.AttributeIndent)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{`<component>`, `Project.xml:1:12: unexpected EOF`},
+		{
+			`<codeStyleSettings language="XML"><indentOptions><option name="INDENT_SIZE" value="wide" /></indentOptions></codeStyleSettings>`,
+			`invalid INDENT_SIZE "wide"`,
+		},
+		{
+			`<codeStyleSettings language="XML"><arrangement><rules>
+<rule><match><AND><NAME>.*</NAME><XML_NAMESPACE>http://(a|b)</XML_NAMESPACE></AND></match></rule>
+</rules></arrangement></codeStyleSettings>`,
+			`unsupported arrangement rule on line 2: namespace pattern "http://(a|b)" isn't a URI`,
+		},
+		{
+			`<codeStyleSettings language="XML"><arrangement><rules>
+<rule><match><AND><NAME>.*:text.*</NAME><XML_NAMESPACE>.*</XML_NAMESPACE></AND></match><order>ANDROID_ATTRIBUTE_ORDER</order></rule>
+</rules></arrangement></codeStyleSettings>`,
+			`ANDROID_ATTRIBUTE_ORDER can only be used for rules which match any name`,
+		},
+		{
+			`<codeStyleSettings language="XML"><arrangement><rules>
+<rule><match><OR><NAME>a</NAME><NAME>b</NAME></OR></match></rule>
+</rules></arrangement></codeStyleSettings>`,
+			`rules with OR can't be used`,
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := Load(writeStyle(t, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("got %v for %q, want error containing %q", err, tt.content, tt.err)
+		}
+	}
+}
+
+func TestAttrRule(t *testing.T) {
+	tests := []struct {
+		name  string
+		ns    string
+		hasNS bool
+		want  string
+	}{
+		{"xmlns:android", "^$", true, "xmlns ^(?:android)$"},
+		{"style", "^$", true, " ^(?:style)$"},
+		{".*:id", "http://schemas.android.com/apk/res/android", true, "http://schemas.android.com/apk/res/android ^(?:id)$"},
+		{"app:.*", ".*", false, "http://schemas.android.com/apk/res-auto ^(?:.*)$"},
+		{".*", ".*", true, "* ^(?:.*)$"},
+		{"name", ".*", false, " ^(?:name)$"},
+	}
+
+	for _, tt := range tests {
+		r, err := attrRule(tt.name, tt.ns, tt.hasNS)
+		if err != nil {
+			t.Errorf("got %v for %q %q", err, tt.name, tt.ns)
+			continue
+		}
+
+		if got := r.Namespace + " " + r.Name.String(); got != tt.want {
+			t.Errorf("got %q for %q %q, want %q", got, tt.name, tt.ns, tt.want)
+		}
+	}
+}
+
+// formatSource formats the document in src with opts
+func formatSource(src []byte, opts printer.Options) ([]byte, error) {
+	root, err := parse.ReadXML(src)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &bytes.Buffer{}
+	err = printer.New(opts).Fprint(w, root)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.Bytes(), nil
+}
+
+func writeStyle(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "Project.xml")
+	err := os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
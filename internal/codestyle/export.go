@@ -0,0 +1,211 @@
+package codestyle
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rsookram/axmlfmt/internal/printer"
+)
+
+// Write writes a project code style, like .idea/codeStyles/Project.xml, whose
+// XML settings format documents as closely to the given options as the IDE
+// allows. Its arrangement rules order attributes the same way, and its
+// indent options match the indents. Attributes which no rule matches are
+// sorted by name with their prefix, rather than by local name, since the IDE
+// can't do that.
+func Write(w io.Writer, opts printer.Options) error {
+	x := &xmlWriter{}
+
+	x.open("component", "name", "ProjectCodeStyleConfiguration")
+	x.open("code_scheme", "name", "Project", "version", "173")
+
+	lineEnding := opts.LineEnding
+	if lineEnding == "" {
+		lineEnding = "\n"
+	}
+	x.empty("option", "name", "LINE_SEPARATOR", "value", lineEnding)
+
+	x.open("XML")
+	x.empty("option", "name", "XML_KEEP_BLANK_LINES", "value", fmt.Sprint(keptBlankLines(opts)))
+	x.empty("option", "name", "XML_ALIGN_ATTRIBUTES", "value", "false")
+	x.empty("option", "name", "XML_SPACE_INSIDE_EMPTY_TAG", "value", "true")
+	x.close("XML")
+
+	x.open("codeStyleSettings", "language", "XML")
+	writeIndentOptions(x, opts)
+
+	x.open("arrangement")
+	x.open("rules")
+	rules := opts.AttrRules()
+	for _, r := range rules {
+		writeRule(x, r)
+	}
+	if len(rules) == 0 || !matchesAll(rules[len(rules)-1]) {
+		// Attributes which no rule matches come last
+		writeRule(x, printer.AttrRule{Namespace: printer.AnyNamespace})
+	}
+	x.close("rules")
+	x.close("arrangement")
+
+	x.close("codeStyleSettings")
+	x.close("code_scheme")
+	x.close("component")
+
+	_, err := w.Write(x.buf.Bytes())
+	return err
+}
+
+// keptBlankLines returns the number of blank lines the IDE keeps, which is
+// the closest to the blank line policy
+func keptBlankLines(opts printer.Options) int {
+	switch opts.BlankLines {
+	case printer.BlankLinesNever:
+		return 0
+	case printer.BlankLinesPreserve:
+		return opts.MaxBlankLines
+	}
+
+	return 1
+}
+
+func writeIndentOptions(x *xmlWriter, opts printer.Options) {
+	attrIndent := opts.AttributeIndent
+	if attrIndent == "" {
+		attrIndent = opts.Indent
+	}
+
+	x.open("indentOptions")
+	if strings.Contains(opts.Indent, "\t") {
+		x.empty("option", "name", "INDENT_SIZE", "value", fmt.Sprint(columns(opts.Indent)))
+		x.empty("option", "name", "CONTINUATION_INDENT_SIZE", "value", fmt.Sprint(columns(attrIndent)))
+		x.empty("option", "name", "TAB_SIZE", "value", "4")
+		x.empty("option", "name", "USE_TAB_CHARACTER", "value", "true")
+	} else {
+		x.empty("option", "name", "INDENT_SIZE", "value", fmt.Sprint(columns(opts.Indent)))
+		x.empty("option", "name", "CONTINUATION_INDENT_SIZE", "value", fmt.Sprint(columns(attrIndent)))
+	}
+	x.close("indentOptions")
+}
+
+// columns returns the width of an indent, where tabs are 4 columns wide
+func columns(indent string) int {
+	return len(indent) + 3*strings.Count(indent, "\t")
+}
+
+// writeRule writes an arrangement rule in its own section which matches the
+// same attributes as r, and sorts them by name
+func writeRule(x *xmlWriter, r printer.AttrRule) {
+	name := pattern(r)
+
+	var qualified, ns string
+	switch r.Namespace {
+	case "xmlns":
+		qualified, ns = "xmlns:"+group(name), "^$"
+	case "":
+		qualified, ns = name, "^$"
+	case printer.AnyNamespace:
+		qualified, ns = anyPrefix+group(name), ".*"
+		if name == ".*" {
+			qualified = name
+		}
+	default:
+		qualified, ns = ".*:"+group(name), namespacePattern(r.Namespace)
+	}
+
+	x.open("section")
+	x.open("rule")
+	x.open("match")
+	x.open("AND")
+	x.text("NAME", qualified)
+	x.empty("XML_ATTRIBUTE")
+	x.text("XML_NAMESPACE", ns)
+	x.close("AND")
+	x.close("match")
+	x.text("order", "BY_NAME")
+	x.close("rule")
+	x.close("section")
+}
+
+// pattern returns the pattern of a rule's name without the anchors which make
+// it match the whole name
+func pattern(r printer.AttrRule) string {
+	if r.Name == nil {
+		return ".*"
+	}
+
+	p := r.Name.String()
+	if strings.HasPrefix(p, "^(?:") && strings.HasSuffix(p, ")$") {
+		return p[len("^(?:") : len(p)-len(")$")]
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(p, "^"), "$")
+}
+
+// group returns p in a group if it has alternatives, so that it can be
+// preceded by a prefix
+func group(p string) string {
+	if strings.Contains(p, "|") {
+		return "(?:" + p + ")"
+	}
+
+	return p
+}
+
+// namespacePattern returns a pattern which matches the namespace URI. Dots
+// are left unescaped, as in the IDE's own rules.
+func namespacePattern(uri string) string {
+	b := &strings.Builder{}
+	for _, r := range uri {
+		if r != '.' && strings.ContainsRune(`\*+?()[]{}|^$`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func matchesAll(r printer.AttrRule) bool {
+	return r.Namespace == printer.AnyNamespace && pattern(r) == ".*"
+}
+
+// xmlWriter writes XML indented by two spaces, like the IDE's settings files
+type xmlWriter struct {
+	buf   bytes.Buffer
+	depth int
+}
+
+func (x *xmlWriter) open(name string, attrs ...string) {
+	x.tag(name, attrs, ">")
+	x.depth++
+}
+
+func (x *xmlWriter) close(name string) {
+	x.depth--
+	fmt.Fprintf(&x.buf, "%s</%s>\n", strings.Repeat("  ", x.depth), name)
+}
+
+func (x *xmlWriter) empty(name string, attrs ...string) {
+	x.tag(name, attrs, " />")
+}
+
+func (x *xmlWriter) text(name, text string) {
+	fmt.Fprintf(&x.buf, "%s<%s>", strings.Repeat("  ", x.depth), name)
+	xml.EscapeText(&x.buf, []byte(text))
+	fmt.Fprintf(&x.buf, "</%s>\n", name)
+}
+
+// tag writes a start tag with the given name and attributes, which alternate
+// between names and values, and ends it with end
+func (x *xmlWriter) tag(name string, attrs []string, end string) {
+	fmt.Fprintf(&x.buf, "%s<%s", strings.Repeat("  ", x.depth), name)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		fmt.Fprintf(&x.buf, ` %s="`, attrs[i])
+		xml.EscapeText(&x.buf, []byte(attrs[i+1]))
+		x.buf.WriteString(`"`)
+	}
+	fmt.Fprintf(&x.buf, "%s\n", end)
+}
@@ -0,0 +1,98 @@
+package codestyle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rsookram/axmlfmt/internal/printer"
+)
+
+const unsorted = `<LinearLayout bind:visible="true" xmlns:bind="http://example.com/bind" android:paddingTop="4dp" style="@style/row" tools:text="x" android:layout_height="wrap_content" xmlns:tools="http://schemas.android.com/tools" app:layout_constraintTop_toTopOf="parent" android:layout_width="match_parent" xmlns:app="http://schemas.android.com/apk/res-auto" xmlns:android="http://schemas.android.com/apk/res/android" android:id="@+id/row" />`
+
+func TestWriteRoundTrip(t *testing.T) {
+	studio, err := printer.AttrOrderPreset("android-studio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := make([]printer.AttrRule, 0)
+	for _, s := range []string{"xmlns:*", "*:/layout_(width|height)/", "android:padding*", "tools:*", "*"} {
+		r, err := printer.ParseAttrRule(s, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		custom = append(custom, r)
+	}
+
+	tests := []struct {
+		name   string
+		indent string
+		order  []printer.AttrRule
+	}{
+		{"default", "    ", nil},
+		{"android-studio", "  ", studio},
+		{"custom", "\t", custom},
+		{"two tabs", "\t\t", nil},
+	}
+
+	for _, tt := range tests {
+		opts := printer.DefaultOptions()
+		opts.Indent = tt.indent
+		opts.AttributeOrder = tt.order
+
+		w := &bytes.Buffer{}
+		err := Write(w, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		path := writeStyle(t, w.String())
+		s, err := Load(path)
+		if err != nil {
+			t.Fatalf("%s: got %v for\n%s", tt.name, err, w.String())
+		}
+
+		imported := printer.DefaultOptions()
+		s.Apply(&imported)
+
+		if imported.Indent != opts.Indent {
+			t.Errorf("%s: got indent %q, want %q", tt.name, imported.Indent, opts.Indent)
+		}
+
+		expected, err := formatSource([]byte(unsorted), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := formatSource([]byte(unsorted), imported)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got, expected) {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", tt.name, got, expected)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	opts := printer.DefaultOptions()
+	opts.BlankLines = printer.BlankLinesNever
+
+	w := &bytes.Buffer{}
+	err := Write(w, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		`<option name="XML_KEEP_BLANK_LINES" value="0" />`,
+		`<option name="INDENT_SIZE" value="4" />`,
+		"<NAME>.*:id</NAME>\n" +
+			"                  <XML_ATTRIBUTE />\n" +
+			"                  <XML_NAMESPACE>http://schemas.android.com/apk/res/android</XML_NAMESPACE>",
+	} {
+		if !strings.Contains(w.String(), expected) {
+			t.Errorf("got:\n%s\nwant it to contain %s", w.String(), expected)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+// Package config loads axmlfmt's project configuration file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/rsookram/axmlfmt/internal/codestyle"
+	"github.com/rsookram/axmlfmt/internal/files"
+	"github.com/rsookram/axmlfmt/internal/printer"
+)
+
+// FileName is the name of the configuration file
+const FileName = ".axmlfmt.toml"
+
+// Config is the contents of a configuration file. Settings which are omitted
+// from the file are left as their zero value.
+type Config struct {
+	// Dir is the directory containing the configuration file. Exclude
+	// patterns are relative to it.
+	Dir string `toml:"-"`
+
+	// Indent is printed once for each level of nesting
+	Indent Indent `toml:"indent"`
+
+	// AttributeIndent is printed after the indent of an element before each
+	// of its attributes which are on their own line
+	AttributeIndent Indent `toml:"attribute_indent"`
+
+	// BlankLines is the name of the printer.BlankLinePolicy to use
+	BlankLines string `toml:"blank_lines"`
+
+	// MaxBlankLines is the largest number of consecutive blank lines kept
+	// with the "preserve" policy
+	MaxBlankLines *int `toml:"max_blank_lines"`
+
+	// Lossless keeps character data and attribute values as they're written
+	// in the source
+	Lossless bool `toml:"lossless"`
+
+	// CodeStyle is the path of an IntelliJ or Android Studio code style
+	// file, such as .idea/codeStyles/Project.xml, relative to Dir. Its XML
+	// settings are used as a base for the other settings.
+	CodeStyle string `toml:"code_style"`
+
+	// Exclude lists patterns of files which aren't formatted. See
+	// files.Find for the syntax.
+	Exclude []string `toml:"exclude"`
+
+	// Namespaces maps prefixes to the namespace URI they're used for
+	Namespaces map[string]string `toml:"namespaces"`
+
+	Attributes Attributes `toml:"attributes"`
+
+	// namespaceOrder is Attributes.NamespaceOrder converted to URIs
+	namespaceOrder []string
+	attributeOrder []printer.AttrRule
+	codeStyle      *codestyle.Style
+	blankLines     printer.BlankLinePolicy
+}
+
+// Attributes configures the order of attributes
+type Attributes struct {
+	// NamespaceOrder is the order of attributes by namespace prefix, where
+	// "xmlns" is used for namespace declarations and "" for attributes
+	// without a namespace
+	NamespaceOrder []string `toml:"namespace_order"`
+
+	// AndroidOrder lists the names of attributes in the android namespace
+	// which come before the others
+	AndroidOrder []string `toml:"android_order"`
+
+	// Order lists the rules which attributes are ordered by, such as
+	// "android:layout_margin*". See printer.ParseAttrRule for the syntax. It
+	// can't be combined with NamespaceOrder and AndroidOrder.
+	Order []string `toml:"order"`
+
+	// Preset is the name of a built-in attribute order to use, such as
+	// "android-studio". See printer.AttrOrderPreset. It can't be combined
+	// with the other settings.
+	Preset string `toml:"preset"`
+}
+
+// Indent is an indent which is given as either a number of spaces or "tab"
+type Indent string
+
+func (i *Indent) UnmarshalTOML(v interface{}) error {
+	var s string
+	switch v := v.(type) {
+	case int64:
+		s = fmt.Sprint(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("invalid indent %v, expected a number of spaces or \"tab\"", v)
+	}
+
+	indent, err := printer.ParseIndent(s)
+	if err != nil {
+		return err
+	}
+
+	*i = Indent(indent)
+	return nil
+}
+
+// Load reads the configuration file at path
+func Load(path string) (*Config, error) {
+	c := &Config{Dir: filepath.Dir(path)}
+
+	md, err := toml.DecodeFile(path, c)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: unknown setting %q", path, undecoded[0].String())
+	}
+
+	err = c.validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return c, nil
+}
+
+func (c *Config) validate() error {
+	if c.CodeStyle != "" {
+		style, err := codestyle.Load(filepath.Join(c.Dir, filepath.FromSlash(c.CodeStyle)))
+		if err != nil {
+			return fmt.Errorf("code_style: %v", err)
+		}
+		c.codeStyle = style
+	}
+
+	if c.BlankLines != "" {
+		policy, err := printer.ParseBlankLinePolicy(c.BlankLines)
+		if err != nil {
+			return err
+		}
+		c.blankLines = policy
+	}
+
+	if c.MaxBlankLines != nil && *c.MaxBlankLines < 0 {
+		return fmt.Errorf("invalid max_blank_lines %d, expected a number which isn't negative", *c.MaxBlankLines)
+	}
+
+	for _, prefix := range c.Attributes.NamespaceOrder {
+		switch prefix {
+		case "xmlns", "":
+			c.namespaceOrder = append(c.namespaceOrder, prefix)
+			continue
+		}
+
+		ns, ok := c.Namespaces[prefix]
+		if !ok {
+			ns, ok = printer.WellKnownNamespace(prefix)
+		}
+		if !ok {
+			return fmt.Errorf("unknown namespace prefix %q in attributes.namespace_order", prefix)
+		}
+
+		c.namespaceOrder = append(c.namespaceOrder, ns)
+	}
+
+	if c.Attributes.Preset != "" {
+		if c.Attributes.Order != nil || c.Attributes.NamespaceOrder != nil || c.Attributes.AndroidOrder != nil {
+			return errors.New("attributes.preset can't be combined with other attribute settings")
+		}
+
+		rules, err := printer.AttrOrderPreset(c.Attributes.Preset)
+		if err != nil {
+			return err
+		}
+		c.attributeOrder = rules
+	}
+
+	if c.Attributes.Order != nil {
+		if c.Attributes.NamespaceOrder != nil || c.Attributes.AndroidOrder != nil {
+			return errors.New("attributes.order can't be combined with attributes.namespace_order or attributes.android_order")
+		}
+
+		rules, err := printer.ParseAttrRules(c.Attributes.Order, c.Namespaces)
+		if err != nil {
+			return fmt.Errorf("%v in attributes.order", err)
+		}
+		c.attributeOrder = rules
+	}
+
+	return nil
+}
+
+// Apply overrides the given options with the settings from the configuration
+// file
+func (c *Config) Apply(opts *printer.Options) {
+	if c.codeStyle != nil {
+		c.codeStyle.Apply(opts)
+	}
+
+	if c.Indent != "" {
+		opts.Indent = string(c.Indent)
+	}
+
+	if c.AttributeIndent != "" {
+		opts.AttributeIndent = string(c.AttributeIndent)
+	}
+
+	if c.BlankLines != "" {
+		opts.BlankLines = c.blankLines
+	}
+
+	if c.MaxBlankLines != nil {
+		opts.MaxBlankLines = *c.MaxBlankLines
+	}
+
+	if c.Lossless {
+		opts.Lossless = true
+	}
+
+	if len(c.Namespaces) > 0 {
+		prefixes := make(map[string]string, len(c.Namespaces))
+		for ns, prefix := range opts.NamespacePrefixes {
+			prefixes[ns] = prefix
+		}
+
+		// Sort so that the result is deterministic when a namespace is given
+		// multiple prefixes
+		sorted := make([]string, 0, len(c.Namespaces))
+		for prefix := range c.Namespaces {
+			sorted = append(sorted, prefix)
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(sorted)))
+
+		for _, prefix := range sorted {
+			prefixes[c.Namespaces[prefix]] = prefix
+		}
+		opts.NamespacePrefixes = prefixes
+	}
+
+	if c.namespaceOrder != nil {
+		opts.NamespaceOrder = c.namespaceOrder
+		opts.AttributeOrder = nil
+	}
+
+	if c.Attributes.AndroidOrder != nil {
+		opts.AndroidAttributeOrder = c.Attributes.AndroidOrder
+		opts.AttributeOrder = nil
+	}
+
+	if c.attributeOrder != nil {
+		opts.AttributeOrder = c.attributeOrder
+	}
+}
+
+// Excludes returns whether the file at path is excluded from formatting
+func (c *Config) Excludes(path string) bool {
+	if len(c.Exclude) == 0 {
+		return false
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(c.Dir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	return files.MatchAny(c.Exclude, filepath.ToSlash(rel))
+}
+
+// Loader finds the configuration files which apply to files. It caches the
+// result for each directory, and is safe for concurrent use.
+type Loader struct {
+	mu sync.Mutex
+	// dirs maps directories to the configuration which applies to them
+	dirs map[string]entry
+}
+
+type entry struct {
+	config *Config
+	err    error
+}
+
+// NewLoader returns a Loader with an empty cache
+func NewLoader() *Loader {
+	return &Loader{dirs: make(map[string]entry)}
+}
+
+// ForFile returns the configuration which applies to the file at path. This
+// is the nearest configuration file found by walking up the directory tree
+// from the file. It returns nil when there's no configuration file.
+func (l *Loader) ForFile(path string) (*Config, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.ForDir(filepath.Dir(abs))
+}
+
+// ForDir returns the configuration which applies to files in dir, which must
+// be an absolute path
+func (l *Loader) ForDir(dir string) (*Config, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	visited := make([]string, 0)
+
+	var found entry
+	for {
+		if e, ok := l.dirs[dir]; ok {
+			found = e
+			break
+		}
+		visited = append(visited, dir)
+
+		path := filepath.Join(dir, FileName)
+		_, err := os.Stat(path)
+		if err == nil {
+			c, err := Load(path)
+			found = entry{config: c, err: err}
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			found = entry{err: err}
+			break
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	for _, d := range visited {
+		l.dirs[d] = found
+	}
+
+	return found.config, found.err
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/rsookram/axmlfmt/internal/printer"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, `
+indent = 2
+attribute_indent = 4
+blank_lines = "never"
+max_blank_lines = 2
+lossless = true
+exclude = ["**/raw/*.xml"]
+
+[namespaces]
+bind = "http://schemas.android.com/apk/res-auto"
+
+[attributes]
+namespace_order = ["xmlns", "android", "", "bind", "tools", "dist"]
+android_order = ["id", "style"]
+`)
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := printer.DefaultOptions()
+	c.Apply(&opts)
+
+	expected := printer.DefaultOptions()
+	expected.Indent = "  "
+	expected.AttributeIndent = "    "
+	expected.NamespacePrefixes = map[string]string{
+		"http://schemas.android.com/apk/res-auto": "bind",
+	}
+	expected.NamespaceOrder = []string{
+		"xmlns",
+		"http://schemas.android.com/apk/res/android",
+		"",
+		"http://schemas.android.com/apk/res-auto",
+		"http://schemas.android.com/tools",
+		"http://schemas.android.com/apk/distribution",
+	}
+	expected.AndroidAttributeOrder = []string{"id", "style"}
+	expected.BlankLines = printer.BlankLinesNever
+	expected.MaxBlankLines = 2
+	expected.Lossless = true
+
+	if !reflect.DeepEqual(opts, expected) {
+		t.Errorf("got %+v, want %+v", opts, expected)
+	}
+}
+
+func TestLoadAttributeOrder(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), `
+[namespaces]
+bind = "http://example.com/bind"
+
+[attributes]
+order = ["style", "android:layout_margin*", "bind:*"]
+`)
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := printer.DefaultOptions()
+	opts.NamespaceOrder = []string{"xmlns"}
+	c.Apply(&opts)
+
+	expected := []printer.AttrRule{
+		{Namespace: "", Name: regexp.MustCompile(`^(?:style)$`)},
+		{Namespace: "http://schemas.android.com/apk/res/android", Name: regexp.MustCompile(`^(?:layout_margin.*)$`)},
+		{Namespace: "http://example.com/bind", Name: regexp.MustCompile(`^(?:.*)$`)},
+	}
+	if len(opts.AttributeOrder) != len(expected) {
+		t.Fatalf("got %d rules, want %d", len(opts.AttributeOrder), len(expected))
+	}
+	for i, r := range opts.AttributeOrder {
+		if r.Namespace != expected[i].Namespace || r.Name.String() != expected[i].Name.String() {
+			t.Errorf("got %s %s, want %s %s", r.Namespace, r.Name, expected[i].Namespace, expected[i].Name)
+		}
+	}
+}
+
+func TestLoadCodeStyle(t *testing.T) {
+	dir := t.TempDir()
+
+	styleDir := filepath.Join(dir, ".idea", "codeStyles")
+	err := os.MkdirAll(styleDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(styleDir, "Project.xml"), []byte(`<component name="ProjectCodeStyleConfiguration">
+  <code_scheme name="Project">
+    <codeStyleSettings language="XML">
+      <indentOptions>
+        <option name="INDENT_SIZE" value="2" />
+        <option name="CONTINUATION_INDENT_SIZE" value="4" />
+      </indentOptions>
+    </codeStyleSettings>
+  </code_scheme>
+</component>`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := writeConfig(t, dir, `
+code_style = ".idea/codeStyles/Project.xml"
+indent = 3
+`)
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := printer.DefaultOptions()
+	c.Apply(&opts)
+
+	// Settings in the configuration file take precedence over the code style
+	if opts.Indent != "   " || opts.AttributeIndent != "    " {
+		t.Errorf("got indents %q and %q, want 3 and 4 spaces", opts.Indent, opts.AttributeIndent)
+	}
+
+	_, err = Load(writeConfig(t, t.TempDir(), `code_style = "missing.xml"`))
+	if err == nil || !strings.Contains(err.Error(), "code_style") {
+		t.Errorf("got %v, want error about code_style", err)
+	}
+}
+
+func TestLoadTabIndent(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), `indent = "tab"`)
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Indent != "\t" {
+		t.Errorf("got %q, want %q", c.Indent, "\t")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{`indnet = 4`, `unknown setting "indnet"`},
+		{`indent = "wide"`, `invalid indent "wide"`},
+		{`indent = 0`, `invalid indent "0"`},
+		{`blank_lines = "sometimes"`, `unknown blank line policy "sometimes"`},
+		{`max_blank_lines = -1`, `invalid max_blank_lines -1`},
+		{"[attributes]\nnamespace_order = [\"custom\"]", `unknown namespace prefix "custom"`},
+		{"[attributes]\norder = [\"custom:*\"]", `unknown namespace prefix "custom"`},
+		{"[attributes]\norder = [\"*\"]\nandroid_order = [\"id\"]", `can't be combined`},
+		{"[attributes]\npreset = \"android-studio\"\norder = [\"*\"]", `can't be combined`},
+		{"[attributes]\npreset = \"eclipse\"", `unknown attribute order preset "eclipse"`},
+	}
+
+	for _, tt := range tests {
+		path := writeConfig(t, t.TempDir(), tt.content)
+
+		_, err := Load(path)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("got %v for %q, want error containing %q", err, tt.content, tt.err)
+		}
+	}
+}
+
+func TestExcludes(t *testing.T) {
+	dir := t.TempDir()
+	c := &Config{Dir: dir, Exclude: []string{"**/raw/*.xml", "lint.xml"}}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(dir, "app", "src", "main", "res", "raw", "data.xml"), true},
+		{filepath.Join(dir, "app", "lint.xml"), true},
+		{filepath.Join(dir, "app", "src", "main", "res", "layout", "main.xml"), false},
+		{filepath.Join(filepath.Dir(dir), "lint.xml"), false},
+	}
+
+	for _, tt := range tests {
+		got := c.Excludes(tt.path)
+		if got != tt.want {
+			t.Errorf("Excludes(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLoaderFindsNearestConfig(t *testing.T) {
+	root := t.TempDir()
+	writeConfig(t, root, `indent = 2`)
+	writeConfig(t, filepath.Join(root, "lib"), `indent = 8`)
+
+	l := NewLoader()
+
+	tests := []struct {
+		path   string
+		indent Indent
+	}{
+		{filepath.Join(root, "app", "src", "main", "AndroidManifest.xml"), "  "},
+		{filepath.Join(root, "lib", "src", "main", "AndroidManifest.xml"), "        "},
+		{filepath.Join(root, "app", "src", "debug", "AndroidManifest.xml"), "  "},
+	}
+
+	for _, tt := range tests {
+		c, err := l.ForFile(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if c == nil || c.Indent != tt.indent {
+			t.Errorf("got %+v for %s, want indent %q", c, tt.path, tt.indent)
+		}
+	}
+}
+
+func writeConfig(t *testing.T, dir, content string) string {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, FileName)
+	err = os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
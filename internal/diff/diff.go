@@ -0,0 +1,291 @@
+// Package diff produces line-based unified diffs.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// context is the number of unchanged lines shown around each change
+const context = 3
+
+// op is a single line of an edit script. kind is one of ' ', '-' or '+'.
+type op struct {
+	kind byte
+	line string
+}
+
+// Unified returns a unified diff between old and new, labelled with oldName
+// and newName in the `---` and `+++` headers. It returns nil when the inputs
+// are equal.
+func Unified(oldName, newName string, old, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+
+	ops := edits(splitLines(old), splitLines(new))
+
+	w := &bytes.Buffer{}
+	fmt.Fprintf(w, "--- %s\n", oldName)
+	fmt.Fprintf(w, "+++ %s\n", newName)
+
+	// aLines and bLines hold the number of lines of each side which come
+	// before the op at a given position
+	aLines := make([]int, len(ops)+1)
+	bLines := make([]int, len(ops)+1)
+	for i, o := range ops {
+		aLines[i+1] = aLines[i]
+		bLines[i+1] = bLines[i]
+		if o.kind != '+' {
+			aLines[i+1]++
+		}
+		if o.kind != '-' {
+			bLines[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend the hunk while the next change is close enough that the
+		// context of both would overlap
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind == ' ' {
+				continue
+			}
+			if j-end > 2*context {
+				break
+			}
+			end = j
+		}
+		end += context + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		fmt.Fprintf(
+			w,
+			"@@ -%s +%s @@\n",
+			hunkRange(aLines[start], aLines[end]-aLines[start]),
+			hunkRange(bLines[start], bLines[end]-bLines[start]),
+		)
+		for _, o := range ops[start:end] {
+			w.WriteByte(o.kind)
+			w.WriteString(o.line)
+			if len(o.line) == 0 || o.line[len(o.line)-1] != '\n' {
+				w.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = end
+	}
+
+	return w.Bytes()
+}
+
+// hunkRange formats the range of a hunk which starts after the given number
+// of lines and spans count lines
+func hunkRange(before, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return fmt.Sprintf("%d", before+1)
+	default:
+		return fmt.Sprintf("%d,%d", before+1, count)
+	}
+}
+
+// splitLines splits b into lines, each keeping its trailing newline
+func splitLines(b []byte) []string {
+	lines := make([]string, 0, bytes.Count(b, []byte("\n"))+1)
+
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			lines = append(lines, string(b))
+			break
+		}
+
+		lines = append(lines, string(b[:i+1]))
+		b = b[i+1:]
+	}
+
+	return lines
+}
+
+// edits returns the shortest edit script which turns a into b
+func edits(a, b []string) []op {
+	d := differ{
+		a:       a,
+		b:       b,
+		deleted: make([]bool, len(a)),
+		added:   make([]bool, len(b)),
+	}
+	d.compare(0, len(a), 0, len(b))
+
+	ops := make([]op, 0, len(a)+len(b))
+	for i, j := 0, 0; i < len(a) || j < len(b); {
+		switch {
+		case i < len(a) && d.deleted[i]:
+			ops = append(ops, op{'-', a[i]})
+			i++
+		case j < len(b) && d.added[j]:
+			ops = append(ops, op{'+', b[j]})
+			j++
+		default:
+			ops = append(ops, op{' ', a[i]})
+			i++
+			j++
+		}
+	}
+
+	return ops
+}
+
+// differ finds the lines which are deleted from a and added to b using the
+// linear space variant of Myers' algorithm
+type differ struct {
+	a, b    []string
+	deleted []bool
+	added   []bool
+}
+
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		aLo++
+		bLo++
+	}
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	if aLo == aHi {
+		for j := bLo; j < bHi; j++ {
+			d.added[j] = true
+		}
+		return
+	}
+	if bLo == bHi {
+		for i := aLo; i < aHi; i++ {
+			d.deleted[i] = true
+		}
+		return
+	}
+
+	x, y, ok := d.split(aLo, aHi, bLo, bHi)
+	if !ok {
+		for i := aLo; i < aHi; i++ {
+			d.deleted[i] = true
+		}
+		for j := bLo; j < bHi; j++ {
+			d.added[j] = true
+		}
+		return
+	}
+
+	d.compare(aLo, x, bLo, y)
+	d.compare(x, aHi, y, bHi)
+}
+
+// split finds the middle snake of the given ranges and returns the point where
+// the ranges can be divided into two smaller problems. It returns false when
+// the ranges have nothing in common.
+func (d *differ) split(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	a := d.a[aLo:aHi]
+	b := d.b[bLo:bHi]
+	n, m := len(a), len(b)
+
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	length := 2*maxD + 2
+
+	forward := make([]int, length)
+	backward := make([]int, length)
+	for i := range forward {
+		forward[i] = -1
+		backward[i] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+
+	delta := n - m
+	// When the difference in length is odd, the forward path is the one
+	// which overlaps the backward path
+	checkForward := delta%2 != 0
+
+	var fStart, fEnd, bStart, bEnd int
+	for step := 0; step < maxD; step++ {
+		for k := -step + fStart; k <= step-fEnd; k += 2 {
+			i := offset + k
+
+			var x int
+			if k == -step || (k != step && forward[i-1] < forward[i+1]) {
+				x = forward[i+1]
+			} else {
+				x = forward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[i] = x
+
+			if x > n {
+				fEnd += 2
+			} else if y > m {
+				fStart += 2
+			} else if checkForward {
+				j := offset + delta - k
+				if j >= 0 && j < length && backward[j] != -1 && x >= n-backward[j] {
+					return aLo + x, bLo + y, true
+				}
+			}
+		}
+
+		for k := -step + bStart; k <= step-bEnd; k += 2 {
+			i := offset + k
+
+			var x int
+			if k == -step || (k != step && backward[i-1] < backward[i+1]) {
+				x = backward[i+1]
+			} else {
+				x = backward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[i] = x
+
+			if x > n {
+				bEnd += 2
+			} else if y > m {
+				bStart += 2
+			} else if !checkForward {
+				j := offset + delta - k
+				if j >= 0 && j < length && forward[j] != -1 {
+					fx := forward[j]
+					fy := offset + fx - j
+					if fx >= n-x {
+						return aLo + fx, bLo + fy, true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
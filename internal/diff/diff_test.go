@@ -0,0 +1,164 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	d := Unified("a", "b", []byte("same\n"), []byte("same\n"))
+	if d != nil {
+		t.Errorf("got %q, want nil", d)
+	}
+}
+
+func TestSingleChange(t *testing.T) {
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+	new := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n"
+
+	d := Unified("a.xml.orig", "a.xml", []byte(old), []byte(new))
+
+	expected := `--- a.xml.orig
++++ a.xml
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`
+	if string(d) != expected {
+		t.Errorf("got\n%s\nwant\n%s", d, expected)
+	}
+}
+
+func TestSeparateHunks(t *testing.T) {
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	new := "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n"
+
+	d := Unified("a", "b", []byte(old), []byte(new))
+
+	expected := `--- a
++++ b
+@@ -1,3 +1,4 @@
++0
+ 1
+ 2
+ 3
+@@ -9,4 +10,3 @@
+ 9
+ 10
+ 11
+-12
+`
+	if string(d) != expected {
+		t.Errorf("got\n%s\nwant\n%s", d, expected)
+	}
+}
+
+func TestMergedHunks(t *testing.T) {
+	old := "1\n2\n3\n4\n5\n6\n7\n"
+	new := "one\n2\n3\n4\n5\n6\nseven\n"
+
+	d := Unified("a", "b", []byte(old), []byte(new))
+
+	expected := `--- a
++++ b
+@@ -1,7 +1,7 @@
+-1
++one
+ 2
+ 3
+ 4
+ 5
+ 6
+-7
++seven
+`
+	if string(d) != expected {
+		t.Errorf("got\n%s\nwant\n%s", d, expected)
+	}
+}
+
+func TestNoNewlineAtEnd(t *testing.T) {
+	d := Unified("a", "b", []byte("<a />"), []byte("<a />\n"))
+
+	expected := `--- a
++++ b
+@@ -1 +1 @@
+-<a />
+\ No newline at end of file
++<a />
+`
+	if string(d) != expected {
+		t.Errorf("got\n%s\nwant\n%s", d, expected)
+	}
+}
+
+func TestEditsAreMinimal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for n := 0; n < 500; n++ {
+		a := randomLines(r)
+		b := randomLines(r)
+
+		ops := edits(a, b)
+
+		var gotA, gotB []string
+		changes := 0
+		for _, o := range ops {
+			if o.kind != '+' {
+				gotA = append(gotA, o.line)
+			}
+			if o.kind != '-' {
+				gotB = append(gotB, o.line)
+			}
+			if o.kind != ' ' {
+				changes++
+			}
+		}
+
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("edits of %q and %q don't reproduce the input: %v", a, b, ops)
+		}
+
+		expected := len(a) + len(b) - 2*lcs(a, b)
+		if changes != expected {
+			t.Fatalf("got %d changes for %q and %q, want %d", changes, a, b, expected)
+		}
+	}
+}
+
+func randomLines(r *rand.Rand) []string {
+	lines := make([]string, r.Intn(20))
+	for i := range lines {
+		lines[i] = string(rune('a'+r.Intn(4))) + "\n"
+	}
+	return lines
+}
+
+// lcs returns the length of the longest common subsequence of a and b
+func lcs(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else if table[i+1][j] > table[i][j+1] {
+				table[i][j] = table[i+1][j]
+			} else {
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	return table[0][0]
+}
@@ -0,0 +1,279 @@
+// Package editorconfig resolves the .editorconfig properties which apply to
+// files, and maps them to printer options.
+//
+// See https://editorconfig.org for the file format.
+package editorconfig
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rsookram/axmlfmt/internal/printer"
+)
+
+// FileName is the name of the files containing properties
+const FileName = ".editorconfig"
+
+// Properties maps lower-case property names to their values. The values of
+// the properties used by axmlfmt are lower-case.
+type Properties map[string]string
+
+// file is a parsed .editorconfig file
+type file struct {
+	root     bool
+	sections []section
+}
+
+type section struct {
+	glob       *glob
+	properties [][2]string
+}
+
+// parse reads the .editorconfig file at path
+func parse(path string) (*file, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dir := filepath.ToSlash(filepath.Dir(path))
+
+	parsed := &file{}
+	var current *section
+
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			g, err := compileGlob(dir, line[1:len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid section %s", path, lineNumber, line)
+			}
+
+			parsed.sections = append(parsed.sections, section{glob: g})
+			current = &parsed.sections[len(parsed.sections)-1]
+			continue
+		}
+
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("%s:%d: invalid line %q", path, lineNumber, line)
+		}
+
+		key := strings.ToLower(strings.TrimSpace(line[:eq]))
+		value := strings.TrimSpace(line[eq+1:])
+
+		if current == nil {
+			// Properties in the preamble apply to the file itself
+			if key == "root" {
+				parsed.root = strings.ToLower(value) == "true"
+			}
+			continue
+		}
+
+		current.properties = append(current.properties, [2]string{key, value})
+	}
+
+	return parsed, scanner.Err()
+}
+
+// Loader resolves the properties which apply to files. It caches the parsed
+// .editorconfig files, and is safe for concurrent use.
+type Loader struct {
+	mu sync.Mutex
+	// files maps directories to the parsed .editorconfig file they contain,
+	// which is nil when there isn't one
+	files map[string]*file
+}
+
+// NewLoader returns a Loader with an empty cache
+func NewLoader() *Loader {
+	return &Loader{files: make(map[string]*file)}
+}
+
+// XMLProperties returns the properties which apply to every XML file in dir.
+// Sections for particular files, like [strings.xml], don't apply.
+func (l *Loader) XMLProperties(dir string) (Properties, error) {
+	// The * in the name is only matched by globs which match any name
+	return l.Properties(filepath.Join(dir, "*.xml"))
+}
+
+// Properties returns the properties which apply to the file at path. Files
+// closer to path take precedence, and the search for files stops at a file
+// with `root = true`.
+func (l *Loader) Properties(path string) (Properties, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := make([]*file, 0)
+	for dir := filepath.Dir(abs); ; {
+		f, err := l.load(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		if f != nil {
+			chain = append(chain, f)
+			if f.root {
+				break
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	slashPath := filepath.ToSlash(abs)
+
+	props := make(Properties)
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, s := range chain[i].sections {
+			if !s.glob.match(slashPath) {
+				continue
+			}
+
+			for _, p := range s.properties {
+				props[p[0]] = p[1]
+			}
+		}
+	}
+
+	for key, value := range props {
+		switch key {
+		case "indent_style", "indent_size", "tab_width", "end_of_line", "insert_final_newline",
+			"ij_continuation_indent_size", "ij_xml_continuation_indent_size":
+			props[key] = strings.ToLower(value)
+		}
+	}
+
+	return props, nil
+}
+
+// load returns the .editorconfig file in dir, or nil if there isn't one
+func (l *Loader) load(dir string) (*file, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if f, ok := l.files[dir]; ok {
+		return f, nil
+	}
+
+	f, err := parse(filepath.Join(dir, FileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		f, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	l.files[dir] = f
+	return f, nil
+}
+
+// Apply overrides the given options with the indent_style, indent_size,
+// tab_width, end_of_line and insert_final_newline properties, as well as the
+// continuation indent from IntelliJ's ij_xml_continuation_indent_size and
+// ij_continuation_indent_size. Properties with values which aren't understood
+// are ignored.
+func (p Properties) Apply(opts *printer.Options) {
+	switch p["indent_style"] {
+	case "tab":
+		opts.Indent = "\t"
+	case "space":
+		if size, ok := p.indentSize(); ok {
+			opts.Indent = strings.Repeat(" ", size)
+		} else if opts.Indent == "\t" {
+			opts.Indent = strings.Repeat(" ", 4)
+		}
+	default:
+		if size, ok := p.indentSize(); ok && opts.Indent != "\t" {
+			opts.Indent = strings.Repeat(" ", size)
+		}
+	}
+
+	if size, ok := p.continuationIndentSize(); ok {
+		if opts.Indent == "\t" {
+			opts.AttributeIndent = strings.Repeat("\t", size/p.tabWidth())
+		} else {
+			opts.AttributeIndent = strings.Repeat(" ", size)
+		}
+	}
+
+	switch p["end_of_line"] {
+	case "lf":
+		opts.LineEnding = "\n"
+	case "crlf":
+		opts.LineEnding = "\r\n"
+	case "cr":
+		opts.LineEnding = "\r"
+	}
+
+	switch p["insert_final_newline"] {
+	case "true":
+		opts.FinalNewline = true
+	case "false":
+		opts.FinalNewline = false
+	}
+}
+
+// indentSize returns the number of columns used for each indentation level
+func (p Properties) indentSize() (int, bool) {
+	size := p["indent_size"]
+	if size == "tab" {
+		size = p["tab_width"]
+	}
+
+	return columns(size)
+}
+
+// continuationIndentSize returns the number of columns used to indent
+// attributes relative to their element
+func (p Properties) continuationIndentSize() (int, bool) {
+	if size, ok := columns(p["ij_xml_continuation_indent_size"]); ok {
+		return size, true
+	}
+
+	return columns(p["ij_continuation_indent_size"])
+}
+
+// tabWidth returns the number of columns a tab takes up
+func (p Properties) tabWidth() int {
+	if width, ok := columns(p["tab_width"]); ok && width > 0 {
+		return width
+	}
+	if size, ok := columns(p["indent_size"]); ok && size > 0 {
+		return size
+	}
+
+	return 4
+}
+
+func columns(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+
+	return n, true
+}
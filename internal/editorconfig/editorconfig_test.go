@@ -0,0 +1,150 @@
+package editorconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rsookram/axmlfmt/internal/printer"
+)
+
+func TestProperties(t *testing.T) {
+	root := t.TempDir()
+
+	write(t, filepath.Join(root, "parent", FileName), `
+[*]
+indent_size = 8
+trim_trailing_whitespace = true
+`)
+	write(t, filepath.Join(root, "parent", "project", FileName), `
+# The parent isn't used
+root = true
+
+[*]
+indent_style = space
+indent_size = 2
+end_of_line = LF
+
+[*.xml]
+indent_size = 4
+insert_final_newline = true
+
+[app/src/main/res/**.xml]
+end_of_line = crlf
+`)
+	write(t, filepath.Join(root, "parent", "project", "app", FileName), `
+[*.xml]
+insert_final_newline = false
+`)
+
+	l := NewLoader()
+
+	props, err := l.Properties(filepath.Join(root, "parent", "project", "app", "src", "main", "res", "values", "strings.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Properties{
+		"indent_style":         "space",
+		"indent_size":          "4",
+		"end_of_line":          "crlf",
+		"insert_final_newline": "false",
+	}
+	if !reflect.DeepEqual(props, expected) {
+		t.Errorf("got %v, want %v", props, expected)
+	}
+}
+
+func TestXMLProperties(t *testing.T) {
+	root := t.TempDir()
+
+	write(t, filepath.Join(root, FileName), `
+[*]
+indent_size = 2
+
+[*.xml]
+indent_style = tab
+
+[strings.xml]
+end_of_line = crlf
+
+[*.kt]
+indent_size = 4
+`)
+
+	props, err := NewLoader().XMLProperties(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Properties{
+		"indent_size":  "2",
+		"indent_style": "tab",
+	}
+	if !reflect.DeepEqual(props, expected) {
+		t.Errorf("got %v, want %v", props, expected)
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		props    Properties
+		expected func(*printer.Options)
+	}{
+		{
+			Properties{"indent_style": "tab", "indent_size": "2"},
+			func(o *printer.Options) { o.Indent = "\t" },
+		},
+		{
+			Properties{"indent_style": "space", "indent_size": "tab", "tab_width": "3"},
+			func(o *printer.Options) { o.Indent = "   " },
+		},
+		{
+			Properties{"indent_size": "2"},
+			func(o *printer.Options) { o.Indent = "  " },
+		},
+		{
+			Properties{"indent_size": "4", "ij_continuation_indent_size": "8"},
+			func(o *printer.Options) { o.AttributeIndent = "        " },
+		},
+		{
+			Properties{"indent_style": "tab", "tab_width": "4", "ij_continuation_indent_size": "4", "ij_xml_continuation_indent_size": "8"},
+			func(o *printer.Options) {
+				o.Indent = "\t"
+				o.AttributeIndent = "\t\t"
+			},
+		},
+		{
+			Properties{"indent_size": "unset", "end_of_line": "crlf", "insert_final_newline": "false"},
+			func(o *printer.Options) {
+				o.LineEnding = "\r\n"
+				o.FinalNewline = false
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		opts := printer.DefaultOptions()
+		tt.props.Apply(&opts)
+
+		expected := printer.DefaultOptions()
+		tt.expected(&expected)
+
+		if !reflect.DeepEqual(opts, expected) {
+			t.Errorf("got %+v for %v, want %+v", opts, tt.props, expected)
+		}
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,199 @@
+package editorconfig
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var numericRange = regexp.MustCompile(`^([+-]?\d+)\.\.([+-]?\d+)$`)
+
+// glob is a compiled section name
+type glob struct {
+	re *regexp.Regexp
+	// ranges are the bounds of the {num1..num2} patterns, in the order of
+	// their capturing groups in re
+	ranges [][2]int
+}
+
+// compileGlob compiles the section name pattern of an .editorconfig file in
+// dir. Patterns without a `/` match files in any subdirectory of dir.
+func compileGlob(dir, pattern string) (*glob, error) {
+	g := &glob{}
+
+	prefix := regexp.QuoteMeta(strings.TrimSuffix(dir, "/")) + "/"
+	if strings.Contains(pattern, "/") {
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else {
+		prefix += "(?:.*/)?"
+	}
+
+	re, err := regexp.Compile("^" + prefix + g.translate(pattern) + "$")
+	if err != nil {
+		return nil, err
+	}
+	g.re = re
+
+	return g, nil
+}
+
+// match returns whether the slash-separated absolute path matches the glob
+func (g *glob) match(path string) bool {
+	m := g.re.FindStringSubmatch(path)
+	if m == nil {
+		return false
+	}
+
+	for i, r := range g.ranges {
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil || n < r[0] || n > r[1] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// translate converts a glob pattern to a regular expression, recording the
+// numeric ranges it contains
+func (g *glob) translate(pattern string) string {
+	b := &strings.Builder{}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+
+		switch c {
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			} else {
+				b.WriteString(`\\`)
+			}
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '/':
+			// `/**/` also matches a single `/`
+			if strings.HasPrefix(pattern[i:], "/**/") {
+				b.WriteString("(?:/|/.*/)")
+				i += 3
+			} else {
+				b.WriteString("/")
+			}
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 || strings.Contains(pattern[i+1:i+1+end], "/") {
+				b.WriteString(`\[`)
+				continue
+			}
+
+			class := pattern[i+1 : i+1+end]
+			b.WriteString("[")
+			if strings.HasPrefix(class, "!") {
+				b.WriteString("^")
+				class = class[1:]
+			}
+			for _, r := range class {
+				if strings.ContainsRune(`\[]^`, r) {
+					b.WriteByte('\\')
+				}
+				b.WriteRune(r)
+			}
+			b.WriteString("]")
+
+			i += end + 1
+		case '{':
+			end := matchingBrace(pattern, i)
+			if end < 0 {
+				b.WriteString(`\{`)
+				continue
+			}
+
+			b.WriteString(g.translateBraces(pattern[i+1 : end]))
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	return b.String()
+}
+
+// translateBraces converts the contents of a {...} pattern
+func (g *glob) translateBraces(content string) string {
+	if m := numericRange.FindStringSubmatch(content); m != nil {
+		lo, _ := strconv.Atoi(m[1])
+		hi, _ := strconv.Atoi(m[2])
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		g.ranges = append(g.ranges, [2]int{lo, hi})
+		return `([+-]?\d+)`
+	}
+
+	alternatives := splitAlternatives(content)
+	if len(alternatives) == 1 {
+		// A single alternative is matched literally
+		return `\{` + g.translate(content) + `\}`
+	}
+
+	translated := make([]string, len(alternatives))
+	for i, a := range alternatives {
+		translated[i] = g.translate(a)
+	}
+	return "(?:" + strings.Join(translated, "|") + ")"
+}
+
+// matchingBrace returns the index of the `}` which closes the `{` at start,
+// or -1 if there isn't one
+func matchingBrace(pattern string, start int) int {
+	depth := 0
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// splitAlternatives splits the contents of a {...} pattern at the commas
+// which aren't nested in another pair of braces
+func splitAlternatives(content string) []string {
+	alternatives := make([]string, 0)
+
+	depth := 0
+	start := 0
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alternatives = append(alternatives, content[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(alternatives, content[start:])
+}
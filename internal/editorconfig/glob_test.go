@@ -0,0 +1,48 @@
+package editorconfig
+
+import "testing"
+
+func TestGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*", "/p/a.xml", true},
+		{"*.xml", "/p/res/layout/a.xml", true},
+		{"*.xml", "/p/a.kt", false},
+		{"*.{xml,kt}", "/p/a.kt", true},
+		{"*.{xml,kt}", "/p/a.java", false},
+		{"res/*.xml", "/p/res/a.xml", true},
+		{"res/*.xml", "/p/res/layout/a.xml", false},
+		{"res/**.xml", "/p/res/layout/a.xml", true},
+		{"/res/**/*.xml", "/p/res/a.xml", true},
+		{"/res/**/*.xml", "/p/res/layout/a.xml", true},
+		{"res/*.xml", "/p/app/res/a.xml", false},
+		{"values-??", "/p/res/values-fr", true},
+		{"values-??", "/p/res/values-fr/x", false},
+		{"[abc].xml", "/p/b.xml", true},
+		{"[!abc].xml", "/p/b.xml", false},
+		{"[!abc].xml", "/p/d.xml", true},
+		{"file{1..3}.xml", "/p/file2.xml", true},
+		{"file{1..3}.xml", "/p/file4.xml", false},
+		{"{single}.xml", "/p/{single}.xml", true},
+		{"{a,{b,c}}.xml", "/p/c.xml", true},
+		{`\*.xml`, "/p/*.xml", true},
+		{`\*.xml`, "/p/a.xml", false},
+		{"a.xml", "/other/a.xml", false},
+	}
+
+	for _, tt := range tests {
+		g, err := compileGlob("/p", tt.pattern)
+		if err != nil {
+			t.Errorf("compileGlob(%q) failed: %v", tt.pattern, err)
+			continue
+		}
+
+		got := g.match(tt.path)
+		if got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v (regexp %s)", tt.pattern, tt.path, got, tt.want, g.re)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package files finds the XML files to format within directories.
+package files
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultInclude is the pattern matching the files which are formatted when
+// no include patterns are given
+const DefaultInclude = "*.xml"
+
+// skippedDirs are the names of directories which contain build output or IDE
+// state rather than source files
+var skippedDirs = map[string]bool{
+	".git":      true,
+	".gradle":   true,
+	".idea":     true,
+	"build":     true,
+	"generated": true,
+}
+
+// Find walks the directory tree rooted at root and returns the paths of the
+// files which match at least one of the include patterns and none of the
+// exclude patterns. Directories which match an exclude pattern, or which
+// contain build output, are skipped.
+//
+// Patterns are matched against the path relative to root, using `/` as the
+// separator. A pattern without a `/` is matched against the base name of
+// the path instead. In addition to the syntax of path.Match, `**` matches any
+// number of directories.
+func Find(root string, include, exclude []string) ([]string, error) {
+	if len(include) == 0 {
+		include = []string{DefaultInclude}
+	}
+
+	found := make([]string, 0)
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if p == root {
+				return nil
+			}
+			if skippedDirs[d.Name()] || MatchAny(exclude, rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if MatchAny(include, rel) && !MatchAny(exclude, rel) {
+			found = append(found, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// MatchAny returns whether name matches any of the given patterns. See Find
+// for the pattern syntax.
+func MatchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if Match(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Match returns whether the slash-separated name matches pattern. See Find
+// for the pattern syntax. Malformed patterns don't match anything.
+func Match(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(name))
+		return matched
+	}
+
+	pattern = strings.TrimPrefix(pattern, "/")
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try consuming every possible number of directories
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		matched, err := path.Match(pattern[0], name[0])
+		if err != nil || !matched {
+			return false
+		}
+
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0
+}
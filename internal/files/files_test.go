@@ -0,0 +1,94 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.xml", "activity_main.xml", true},
+		{"*.xml", "src/main/res/layout/activity_main.xml", true},
+		{"*.xml", "build.gradle", false},
+		{"layout/*.xml", "layout/main.xml", true},
+		{"layout/*.xml", "res/layout/main.xml", false},
+		{"**/layout/*.xml", "res/layout/main.xml", true},
+		{"**/layout/*.xml", "layout/main.xml", true},
+		{"res/**", "res/values/strings.xml", true},
+		{"res/**/strings.xml", "res/values-fr/strings.xml", true},
+		{"/res/*.xml", "res/a.xml", true},
+		{"values-*", "res/values-fr", true},
+		{"[", "[", false},
+	}
+
+	for _, tt := range tests {
+		got := Match(tt.pattern, tt.name)
+		if got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+
+	for _, name := range []string{
+		"AndroidManifest.xml",
+		"res/layout/main.xml",
+		"res/values/strings.xml",
+		"res/values/notes.txt",
+		"res/raw/data.xml",
+		"build/intermediates/merged.xml",
+		".idea/workspace.xml",
+		"lib/build/generated.xml",
+	} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(p), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(p, []byte("<a />\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	{
+		found, err := Find(root, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := paths(root, "AndroidManifest.xml", "res/layout/main.xml", "res/raw/data.xml", "res/values/strings.xml")
+		if !reflect.DeepEqual(found, expected) {
+			t.Errorf("got %v, want %v", found, expected)
+		}
+	}
+
+	{
+		found, err := Find(root, []string{"res/**/*.xml"}, []string{"raw", "strings.xml"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := paths(root, "res/layout/main.xml")
+		if !reflect.DeepEqual(found, expected) {
+			t.Errorf("got %v, want %v", found, expected)
+		}
+	}
+}
+
+func paths(root string, names ...string) []string {
+	pp := make([]string, len(names))
+	for i, name := range names {
+		pp[i] = filepath.Join(root, filepath.FromSlash(name))
+	}
+
+	return pp
+}
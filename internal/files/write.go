@@ -0,0 +1,69 @@
+package files
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+)
+
+// WriteAtomic replaces the contents of the file at name with data. The data
+// is written to a temporary file in the same directory which is renamed over
+// the original once it's complete, so the original is never left truncated or
+// partially written. The original file's permissions are kept. When the file
+// already contains data, it's left untouched so that its modification time
+// doesn't change. Symbolic links are followed so that the file they point to
+// is replaced rather than the link.
+func WriteAtomic(name string, data []byte) (err error) {
+	name, err = filepath.EvalSymlinks(name)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	existing, err := os.ReadFile(name)
+	if err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+
+	dir, base := filepath.Split(name)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+base+".axmlfmt-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		return err
+	}
+
+	err = tmp.Chmod(info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	err = tmp.Sync()
+	if err != nil {
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,76 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "strings.xml")
+
+	err := os.WriteFile(name, []byte("<resources/>"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = WriteAtomic(name, []byte("<resources />\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "<resources />\n" {
+		t.Errorf("got %q, want %q", content, "<resources />\n")
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("got mode %v, want %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("got %d files in directory, want 1", len(entries))
+	}
+}
+
+func TestWriteAtomicUnchanged(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "strings.xml")
+
+	err := os.WriteFile(name, []byte("<resources />\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	err = os.Chtimes(name, past, past)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = WriteAtomic(name, []byte("<resources />\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("got modification time %v, want %v", info.ModTime(), past)
+	}
+}
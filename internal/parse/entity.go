@@ -0,0 +1,65 @@
+package parse
+
+import "regexp"
+
+// entityDecl matches the declaration of a general entity in a document type
+// definition, with either its replacement text or an external identifier
+var entityDecl = regexp.MustCompile(`<!ENTITY\s+([^\s%"'>]+)\s+(?:"([^"]*)"|'([^']*)'|(?:SYSTEM|PUBLIC)\b)`)
+
+// entityRef matches a reference to a named entity
+var entityRef = regexp.MustCompile(`&([^\s&;#]+);`)
+
+// predefinedEntities are the entities which every XML document can reference
+var predefinedEntities = map[string]bool{
+	"lt":   true,
+	"gt":   true,
+	"amp":  true,
+	"apos": true,
+	"quot": true,
+}
+
+// declareEntities adds the general entities declared in the directive to
+// entities. External entities can't be resolved, so they're declared with
+// empty replacement text.
+func declareEntities(entities map[string]string, directive []byte) {
+	for _, m := range entityDecl.FindAllSubmatch(directive, -1) {
+		name := string(m[1])
+		if _, ok := entities[name]; ok {
+			// The first declaration is binding
+			continue
+		}
+
+		entities[name] = string(m[2]) + string(m[3])
+	}
+}
+
+// referencesEntities returns whether the raw text references any of the
+// entities which are declared in the document
+func referencesEntities(raw string, entities map[string]string) bool {
+	if len(entities) == 0 {
+		return false
+	}
+
+	for _, m := range entityRef.FindAllStringSubmatch(raw, -1) {
+		if predefinedEntities[m[1]] {
+			continue
+		}
+		if _, ok := entities[m[1]]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// attrsReferenceEntities returns whether any of the raw attribute values
+// reference entities which are declared in the document
+func attrsReferenceEntities(attrs []Attr, entities map[string]string) bool {
+	for _, a := range attrs {
+		if referencesEntities(a.Raw, entities) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,92 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error is an error in the syntax of a document, along with where it occurred
+type Error struct {
+	// Filename is the name of the document, which is empty when unknown
+	Filename string
+	// Line and Column are the 1-based position of the error. Columns are
+	// counted in characters.
+	Line   int
+	Column int
+	Msg    string
+	// Excerpt is the line of the source containing the error, followed by a
+	// line with a caret pointing at the column
+	Excerpt string
+}
+
+func (e *Error) Error() string {
+	pos := fmt.Sprintf("%d:%d", e.Line, e.Column)
+	if e.Filename != "" {
+		pos = e.Filename + ":" + pos
+	}
+
+	if e.Excerpt == "" {
+		return fmt.Sprintf("%s: %s", pos, e.Msg)
+	}
+
+	return fmt.Sprintf("%s: %s\n%s", pos, e.Msg, e.Excerpt)
+}
+
+// newError returns an Error for a problem at the given offset in src
+func newError(src []byte, offset int64, msg string) *Error {
+	if offset > int64(len(src)) {
+		offset = int64(len(src))
+	}
+	before := src[:offset]
+
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	lineEnd := bytes.IndexByte(src[lineStart:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(src)
+	} else {
+		lineEnd += lineStart
+	}
+
+	line := string(bytes.TrimRight(src[lineStart:lineEnd], "\r"))
+	prefix := string(before[lineStart:])
+
+	return &Error{
+		Line:    bytes.Count(before, []byte("\n")) + 1,
+		Column:  utf8.RuneCountInString(prefix) + 1,
+		Msg:     msg,
+		Excerpt: excerpt(line, prefix),
+	}
+}
+
+// excerpt returns the line followed by a caret under the character after
+// prefix. Tabs are kept so that the caret lines up.
+func excerpt(line, prefix string) string {
+	if strings.TrimSpace(line) == "" {
+		return ""
+	}
+
+	caret := &strings.Builder{}
+	for _, r := range prefix {
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	caret.WriteRune('^')
+
+	return line + "\n" + caret.String()
+}
+
+// syntaxError converts an error from the decoder into an Error
+func syntaxError(src []byte, offset int64, err error) *Error {
+	msg := err.Error()
+	if se, ok := err.(*xml.SyntaxError); ok {
+		msg = se.Msg
+	}
+
+	return newError(src, offset, msg)
+}
@@ -0,0 +1,73 @@
+package parse
+
+import "strings"
+
+// Comments which turn formatting off and on for the nodes between them, or
+// off for the node after the comment
+const (
+	formatOff  = "axmlfmt:off"
+	formatOn   = "axmlfmt:on"
+	ignoreNext = "axmlfmt:ignore-next"
+)
+
+// keepIgnored replaces the nodes which formatting is turned off for with
+// verbatim nodes holding their source. Everything after `axmlfmt:off` is
+// ignored up to `axmlfmt:on` or the end of the parent, and
+// `axmlfmt:ignore-next` ignores the node after it. The comments themselves
+// are formatted as usual.
+func keepIgnored(n *Node, src []byte) {
+	children := make([]*Node, 0, len(n.Children))
+
+	for i := 0; i < len(n.Children); i++ {
+		c := n.Children[i]
+		children = append(children, c)
+
+		if c.Type != CommentNode {
+			keepIgnored(c, src)
+			continue
+		}
+
+		end := i
+		switch strings.TrimSpace(c.Data) {
+		case formatOff:
+			end = len(n.Children)
+			for j := i + 1; j < len(n.Children); j++ {
+				if isComment(n.Children[j], formatOn) {
+					end = j
+					break
+				}
+			}
+		case ignoreNext:
+			if i+1 < len(n.Children) {
+				end = i + 2
+			}
+		}
+
+		if end > i+1 {
+			children = append(children, verbatim(n.Children[i+1:end], src))
+			i = end - 1
+		}
+	}
+
+	n.Children = children
+}
+
+// verbatim returns a node which stands in for the given siblings
+func verbatim(nodes []*Node, src []byte) *Node {
+	first := nodes[0]
+	last := nodes[len(nodes)-1]
+
+	return &Node{
+		Type:           VerbatimNode,
+		Parent:         first.Parent,
+		Namespaces:     first.Namespaces,
+		Data:           rawText(src[first.Start:last.End]),
+		NewLinesBefore: first.NewLinesBefore,
+		Start:          first.Start,
+		End:            last.End,
+	}
+}
+
+func isComment(n *Node, data string) bool {
+	return n.Type == CommentNode && strings.TrimSpace(n.Data) == data
+}
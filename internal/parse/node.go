@@ -0,0 +1,97 @@
+package parse
+
+import "encoding/xml"
+
+// NodeType is the kind of a Node
+type NodeType int
+
+const (
+	// DocumentNode is the root of the tree, whose children are the top-level
+	// nodes of the document
+	DocumentNode NodeType = iota
+	ElementNode
+	TextNode
+	CommentNode
+	ProcInstNode
+	DirectiveNode
+	// VerbatimNode is a part of the document which formatting is turned off
+	// for. Its Data is the source of the nodes it replaces.
+	VerbatimNode
+)
+
+// Node is a node in the syntax tree of a document. Along with the content of
+// the document, it records trivia from the source, such as new lines and how
+// text and attribute values are written, so that formatting can be lossless.
+type Node struct {
+	Type     NodeType
+	Parent   *Node
+	Children []*Node
+
+	// Name is the name of an element
+	Name xml.Name
+	// Attrs are the attributes of an element, in the order of the source
+	Attrs []Attr
+	// Namespaces maps the prefixes which are in scope for the node to the
+	// namespace URI they're bound to. The default namespace uses the empty
+	// prefix. It's nil when no namespaces are declared.
+	Namespaces map[string]string
+
+	// Data is the content of text, comments and directives, without their
+	// delimiters, the instruction of processing instructions and the source
+	// of verbatim nodes
+	Data string
+	// Target is the target of a processing instruction
+	Target string
+
+	// TextContent is whether the content of an element is text, possibly
+	// with markup in it, such as in a string resource. Whitespace in it is
+	// significant, and its children are printed inline.
+	TextContent bool
+
+	// IsCDATA is whether text was a CDATA section in the source
+	IsCDATA bool
+	// RawText is the source of text, including any entity and character
+	// references and CDATA markers
+	RawText string
+	// EntityReferences is whether text or the attribute values of an
+	// element reference entities declared in the document type definition.
+	// They're printed as written in the source so that the references are
+	// kept.
+	EntityReferences bool
+
+	// NewLinesBefore is the number of new lines in the whitespace between
+	// the previous node and this one in the source
+	NewLinesBefore int
+	// BlankLinesBefore is the number of blank lines printed before the node.
+	// It's determined by the printer's blank line policy.
+	BlankLinesBefore int
+
+	// Start and End are the byte offsets of the node in the source. The
+	// range of an element includes its end tag.
+	Start int64
+	End   int64
+}
+
+// Attr is an attribute of an element
+type Attr struct {
+	xml.Attr
+
+	// Raw is the value as it's written in the source, without quotes
+	Raw string
+	// Quote is the quote character around the value in the source, or 0 for
+	// attributes which aren't from the source
+	Quote byte
+}
+
+// AppendChild adds c as the last child of n
+func (n *Node) AppendChild(c *Node) {
+	c.Parent = n
+	n.Children = append(n.Children, c)
+}
+
+// IsInline returns whether the node is part of the text content of its
+// parent, such as `<xliff:g>` in a `<string>`. Inline nodes are printed
+// without indentation or new lines.
+func (n *Node) IsInline() bool {
+	return n.Parent != nil && n.Parent.TextContent
+}
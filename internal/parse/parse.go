@@ -1,108 +1,166 @@
 package parse
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"strings"
 )
 
-// ReadXML processes tokens from the given reader and returns a slice of
-// Elements corresponding to the tokens
-func ReadXML(reader xml.TokenReader) ([]Element, error) {
-	stack := make([]*Element, 0)
-	elements := make([]*Element, 0)
+// cdataStart begins a CDATA section
+var cdataStart = []byte("<![CDATA[")
 
-	for t, err := reader.Token(); ; t, err = reader.Token() {
+// ReadXML parses the given document and returns the root of its syntax tree,
+// which is a DocumentNode. Errors in the document are returned as an *Error.
+func ReadXML(src []byte) (*Node, error) {
+	reader := xml.NewDecoder(bytes.NewReader(src))
+	// Entities declared in the document type definition are added as
+	// they're found so that references to them can be decoded
+	reader.Entity = make(map[string]string)
+
+	doc := &Node{Type: DocumentNode, End: int64(len(src))}
+	parent := doc
+
+	// newLines counts the new lines in the whitespace since the last node
+	newLines := 0
+
+	for {
+		offset := reader.InputOffset()
+
+		t, err := reader.Token()
 		if err == io.EOF {
-			return elementsCopy(elements), nil
+			dropInsignificantSpace(doc)
+			keepIgnored(doc, src)
+			return doc, nil
 		}
 		if err != nil {
-			return nil, err
+			return nil, syntaxError(src, reader.InputOffset(), err)
 		}
+		end := reader.InputOffset()
+		source := src[offset:end]
 
-		depth := len(stack)
+		// The decoder doesn't distinguish CDATA sections from other
+		// character data, so check the source
+		_, isCharData := t.(xml.CharData)
+		isCDATA := isCharData && bytes.HasPrefix(source, cdataStart)
+		isSpace := isCharData && !isCDATA && isWhitespace(t.(xml.CharData))
+
+		if isSpace && parent == doc {
+			newLines += strings.Count(string(t.(xml.CharData)), "\n")
+			continue
+		}
+
+		node := &Node{
+			Namespaces:     parent.Namespaces,
+			NewLinesBefore: newLines,
+			Start:          offset,
+			End:            end,
+		}
+		newLines = 0
 
 		switch token := t.(type) {
 		case xml.StartElement:
-			containsCharData := false
-			if depth > 0 {
-				parent := stack[len(stack)-1]
-				parent.IsSelfClosing = false
-				containsCharData = parent.ContainsCharData
-			}
+			node.Type = ElementNode
+			node.Name = token.Name
+			node.Attrs = attrs(token.Attr, source)
+			node.Namespaces = declareNamespaces(parent.Namespaces, token.Attr)
+			node.EntityReferences = attrsReferenceEntities(node.Attrs, reader.Entity)
 
-			ele := Element{
-				Token:            xml.CopyToken(token),
-				Depth:            depth,
-				IsSelfClosing:    true,
-				ContainsCharData: containsCharData,
-			}
-			elements = append(elements, &ele)
-
-			stack = append(stack, &ele)
+			parent.AppendChild(node)
+			parent = node
+			continue
 		case xml.EndElement:
-			start := stack[len(stack)-1]
-
-			stack = stack[:len(stack)-1]
-
-			// No end is needed for empty nodes
-			if !start.IsSelfClosing || start.ContainsCharData {
-				newDepth := len(stack)
-
-				ele := Element{
-					Token:            xml.CopyToken(token),
-					Depth:            newDepth,
-					IsSelfClosing:    false,
-					ContainsCharData: start.ContainsCharData,
-				}
-				elements = append(elements, &ele)
-			}
+			parent.End = end
+			parent = parent.Parent
+			continue
 		case xml.CharData:
-			s := string(token)
-			if len(strings.TrimSpace(s)) != 0 {
-				if depth == 0 {
-					return nil, fmt.Errorf("unexpected top-level char data `%s`", s)
-				}
-
-				parent := stack[len(stack)-1]
-				parent.ContainsCharData = true
-
-				ele := Element{
-					Token:         xml.CopyToken(xml.CharData(s)),
-					Depth:         len(stack),
-					IsSelfClosing: false,
-				}
-				elements = append(elements, &ele)
+			if parent == doc {
+				start := offset + int64(len(source)-len(bytes.TrimLeft(source, " \t\r\n")))
+				return nil, newError(src, start, fmt.Sprintf("unexpected top-level char data `%s`", string(token)))
 			}
+
+			node.Type = TextNode
+			node.Data = string(token)
+			node.IsCDATA = isCDATA
+			node.RawText = rawText(source)
+			node.EntityReferences = !isCDATA && referencesEntities(node.RawText, reader.Entity)
 		case xml.Comment:
-			if depth > 0 {
-				parent := stack[len(stack)-1]
-				parent.IsSelfClosing = false
-			}
+			node.Type = CommentNode
+			node.Data = string(token)
+		case xml.Directive:
+			// The decoder drops comments in directives, so they're taken
+			// from the source without "<!" and ">"
+			directive := source[2 : len(source)-1]
+			declareEntities(reader.Entity, directive)
 
-			ele := Element{
-				Token:         xml.CopyToken(token),
-				Depth:         depth,
-				IsSelfClosing: false,
-			}
-			elements = append(elements, &ele)
+			node.Type = DirectiveNode
+			node.Data = rawText(directive)
 		case xml.ProcInst:
-			ele := Element{
-				Token:         xml.CopyToken(token),
-				Depth:         depth,
-				IsSelfClosing: false,
-			}
-			elements = append(elements, &ele)
+			node.Type = ProcInstNode
+			node.Target = token.Target
+			node.Data = string(token.Inst)
+		default:
+			continue
+		}
+
+		parent.AppendChild(node)
+	}
+}
+
+// attrs returns the attributes of a start element along with how they're
+// written in tag, the source of the start element
+func attrs(attrs []xml.Attr, tag []byte) []Attr {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	raw := scanAttrValues(tag)
+	if len(raw) != len(attrs) {
+		raw = nil
+	}
+
+	ret := make([]Attr, len(attrs))
+	for i, a := range attrs {
+		ret[i] = Attr{Attr: a}
+		if raw != nil {
+			ret[i].Raw = rawText(raw[i].value)
+			ret[i].Quote = raw[i].quote
 		}
 	}
+
+	return ret
 }
 
-func elementsCopy(ele []*Element) []Element {
-	eleCopy := make([]Element, len(ele))
-	for i, e := range ele {
-		eleCopy[i] = *e
+// declareNamespaces returns the namespaces in scope for an element with the
+// given attributes, whose parent has the given scope. The parent's scope is
+// returned as-is when the element doesn't declare any namespaces.
+func declareNamespaces(scope map[string]string, attrs []xml.Attr) map[string]string {
+	var declared map[string]string
+
+	for _, a := range attrs {
+		var prefix string
+		switch {
+		case a.Name.Space == "xmlns":
+			prefix = a.Name.Local
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			prefix = ""
+		default:
+			continue
+		}
+
+		if declared == nil {
+			declared = make(map[string]string, len(scope)+1)
+			for p, ns := range scope {
+				declared[p] = ns
+			}
+		}
+		declared[prefix] = a.Value
+	}
+
+	if declared == nil {
+		return scope
 	}
 
-	return eleCopy
+	return declared
 }
@@ -1,8 +1,8 @@
 package parse
 
 import (
-	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
@@ -13,27 +13,51 @@ const androidNS = "http://schemas.android.com/apk/res/android"
 func TestEmptyXML(t *testing.T) {
 	doc := ""
 
-	ee, err := read(doc)
+	root, err := read(doc)
 	if err != nil {
 		t.Errorf("got %s", err)
 	}
 
-	if len(ee) != 0 {
-		t.Errorf("got %d, want %d", len(ee), 0)
+	if len(root.Children) != 0 {
+		t.Errorf("got %d, want %d", len(root.Children), 0)
 	}
 }
 
 func TestTopLevelCharData(t *testing.T) {
 	doc := `test`
 
-	ee, err := read(doc)
-	if ee != nil {
-		t.Errorf("expected error, got %s", str(ee))
+	root, err := read(doc)
+	if root != nil {
+		t.Errorf("expected error, got %s", dump(root))
 	}
 
-	expected := "unexpected top-level char data `test`"
-	if err.Error() != expected {
-		t.Errorf("got %s, want %s", err.Error(), expected)
+	expected := &Error{
+		Line:    1,
+		Column:  1,
+		Msg:     "unexpected top-level char data `test`",
+		Excerpt: "test\n^",
+	}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("got %#v, want %#v", err, expected)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	doc := "<resources>\n\t<string name=\"a\">é</strin>\n</resources>\n"
+
+	_, err := read(doc)
+
+	perr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("got %#v, want *Error", err)
+	}
+	perr.Filename = "strings.xml"
+
+	expected := "strings.xml:2:28: element <string> closed by </strin>\n" +
+		"\t<string name=\"a\">é</strin>\n" +
+		"\t                          ^"
+	if perr.Error() != expected {
+		t.Errorf("got %q, want %q", perr.Error(), expected)
 	}
 }
 
@@ -44,34 +68,24 @@ func TestCharDataWithCDATA(t *testing.T) {
 	</string>
 	`
 
-	ee, err := read(doc)
+	root, err := read(doc)
 	if err != nil {
 		t.Errorf("got %s", err)
 	}
 
-	expected := []Element{
-		{
-			Token: xml.StartElement{
-				Name: tagName("", "string"),
-				Attr: []xml.Attr{},
-			},
-			// TODO: Ideally IsSelfClosing and ContainsCharData shouldn't both be true at the same time, but
-			// right now, it doesn't matter in practice (ContainsCharData takes precedence)
-			IsSelfClosing:    true,
-			ContainsCharData: true,
-		},
-		{
-			Token: xml.CharData("<i>"),
-			Depth: 1,
-		},
-		{
-			Token:            endElement("", "string"),
-			ContainsCharData: true,
-		},
+	// Whitespace is part of the text of a string
+	expected := `element string text
+  text "\n\t\t"
+  text "<i>" cdata
+  text "\n\t"
+`
+	if dump(root) != expected {
+		t.Errorf("got %s, want %s", dump(root), expected)
 	}
 
-	if !equal(expected, ee) {
-		t.Errorf("got %s, want %s", str(ee), str(expected))
+	cdata := root.Children[0].Children[1]
+	if cdata.RawText != "<![CDATA[<i>]]>" {
+		t.Errorf("got %q, want %q", cdata.RawText, "<![CDATA[<i>]]>")
 	}
 }
 
@@ -85,56 +99,353 @@ func TestElementContainingCommentNoChildren(t *testing.T) {
 </shape>
 	`
 
-	ee, err := read(doc)
+	root, err := read(doc)
 	if err != nil {
 		t.Errorf("got %s", err)
 	}
 
-	expected := []Element{
-		{
-			Token: xml.StartElement{
-				Name: tagName("", "shape"),
-				Attr: []xml.Attr{
-					attr("xmlns", "android", androidNS),
-					attr(androidNS, "shape", "rectangle"),
-				}},
-		},
-		{
-			Token: xml.Comment(" no children "),
-			Depth: 1,
-		},
-		{
-			Token: endElement("", "shape"),
-		},
+	expected := `element shape
+  comment " no children "
+`
+	if dump(root) != expected {
+		t.Errorf("got %s, want %s", dump(root), expected)
+	}
+
+	shape := root.Children[0]
+	expectedAttrs := []Attr{
+		{Attr: attr("xmlns", "android", androidNS), Raw: androidNS, Quote: '"'},
+		{Attr: attr(androidNS, "shape", "rectangle"), Raw: "rectangle", Quote: '"'},
+	}
+	if !reflect.DeepEqual(shape.Attrs, expectedAttrs) {
+		t.Errorf("got %+v, want %+v", shape.Attrs, expectedAttrs)
+	}
+
+	scope := map[string]string{"android": androidNS}
+	for _, n := range []*Node{shape, shape.Children[0]} {
+		if !reflect.DeepEqual(n.Namespaces, scope) {
+			t.Errorf("got %v, want %v", n.Namespaces, scope)
+		}
 	}
 
-	if !equal(expected, ee) {
-		t.Errorf("got %s, want %s", str(ee), str(expected))
+	if shape.Parent != root || shape.Children[0].Parent != shape {
+		t.Errorf("got wrong parents")
 	}
 }
 
-func read(doc string) ([]Element, error) {
-	d := xml.NewDecoder(strings.NewReader(doc))
-	return ReadXML(d)
+func TestNamespaceScopes(t *testing.T) {
+	doc := `
+<layout xmlns:android="http://schemas.android.com/apk/res/android">
+    <data xmlns:bind="http://example.com/bind" />
+    <svg xmlns="http://www.w3.org/2000/svg" />
+</layout>
+	`
+
+	root, err := read(doc)
+	if err != nil {
+		t.Errorf("got %s", err)
+	}
+
+	layout := map[string]string{"android": androidNS}
+	data := map[string]string{"android": androidNS, "bind": "http://example.com/bind"}
+	svg := map[string]string{"android": androidNS, "": "http://www.w3.org/2000/svg"}
+
+	expected := []map[string]string{layout, data, svg}
+	nodes := all(root)
+	if len(nodes) != len(expected) {
+		t.Fatalf("got %s, want %d nodes", dump(root), len(expected))
+	}
+
+	for i, n := range nodes {
+		if !reflect.DeepEqual(n.Namespaces, expected[i]) {
+			t.Errorf("got %v for node %d, want %v", n.Namespaces, i, expected[i])
+		}
+	}
 }
 
-func attr(space, local, value string) xml.Attr {
-	return xml.Attr{Name: tagName(space, local), Value: value}
+func TestNewLinesBefore(t *testing.T) {
+	doc := `<resources>
+    <string name="a">A</string>
+
+
+    <!-- Section -->
+    <string name="b">B</string><string name="c">C</string>
+</resources>`
+
+	root, err := read(doc)
+	if err != nil {
+		t.Errorf("got %s", err)
+	}
+
+	expected := []int{0, 1, 0, 3, 1, 0, 0, 0}
+	nodes := all(root)
+	if len(nodes) != len(expected) {
+		t.Fatalf("got %s, want %d nodes", dump(root), len(expected))
+	}
+
+	for i, n := range nodes {
+		if n.NewLinesBefore != expected[i] {
+			t.Errorf("got %d new lines before node %d, want %d", n.NewLinesBefore, i, expected[i])
+		}
+	}
 }
 
-func endElement(space, local string) xml.EndElement {
-	return xml.EndElement{Name: tagName(space, local)}
+func TestOffsets(t *testing.T) {
+	doc := `<resources>
+    <string name="a">A</string>
+    <item />
+</resources>
+`
+
+	root, err := read(doc)
+	if err != nil {
+		t.Fatalf("got %s", err)
+	}
+
+	expected := []string{
+		doc[:len(doc)-1],
+		`<string name="a">A</string>`,
+		"A",
+		"<item />",
+	}
+
+	nodes := all(root)
+	for i, n := range nodes {
+		if got := doc[n.Start:n.End]; got != expected[i] {
+			t.Errorf("got %q for node %d, want %q", got, i, expected[i])
+		}
+	}
+}
+
+func TestSignificantWhitespace(t *testing.T) {
+	doc := `<resources>
+    <string name="a"><b>Tap</b> <i>here</i></string>
+    <plurals name="b">
+        <item quantity="one"> </item>
+    </plurals>
+    <string-array name="c">
+        <item>&#160;</item>
+    </string-array>
+    <LinearLayout xml:space="preserve">
+        <TextView />
+    </LinearLayout>
+    <LinearLayout>
+        <TextView />
+    </LinearLayout>
+</resources>`
+
+	root, err := read(doc)
+	if err != nil {
+		t.Fatalf("got %s", err)
+	}
+
+	expected := `element resources
+  element string text
+    element b text
+      text "Tap"
+    text " "
+    element i text
+      text "here"
+  element plurals
+    element item text
+      text " "
+  element string-array
+    element item text
+      text "\u00a0"
+  element LinearLayout text
+    text "\n        "
+    element TextView
+    text "\n    "
+  element LinearLayout
+    element TextView
+`
+	if dump(root) != expected {
+		t.Errorf("got %s, want %s", dump(root), expected)
+	}
+}
+
+func TestDirective(t *testing.T) {
+	doc := `<!DOCTYPE resources [
+    <!-- Shared names -->
+    <!ENTITY app "MyApp">
+    <!ENTITY terms SYSTEM "terms.txt">
+]>
+<resources>
+    <string name="a" label="&app;">Welcome to &app; &amp; &terms;</string>
+    <string name="b">Plain &amp; simple</string>
+</resources>`
+
+	root, err := read(doc)
+	if err != nil {
+		t.Fatalf("got %s", err)
+	}
+
+	expected := `directive "DOCTYPE resources [\n    <!-- Shared names -->\n    <!ENTITY app \"MyApp\">\n    <!ENTITY terms SYSTEM \"terms.txt\">\n]"
+element resources
+  element string text
+    text "Welcome to MyApp & "
+  element string text
+    text "Plain & simple"
+`
+	if dump(root) != expected {
+		t.Errorf("got %s, want %s", dump(root), expected)
+	}
+
+	resources := root.Children[1]
+	tests := []struct {
+		node       *Node
+		references bool
+	}{
+		{resources.Children[0], true},
+		{resources.Children[0].Children[0], true},
+		{resources.Children[1], false},
+		{resources.Children[1].Children[0], false},
+	}
+
+	for i, tt := range tests {
+		if tt.node.EntityReferences != tt.references {
+			t.Errorf("got %t for node %d, want %t", tt.node.EntityReferences, i, tt.references)
+		}
+	}
+}
+
+func TestRawValues(t *testing.T) {
+	doc := `<resources>
+    <string name='quote' tools:ignore = "x&apos;y">He said &quot;hi&quot;&#8230;&#160;&#x2014;</string>
+    <string name="crlf" value='a "b"'>a&amp;b` + "\r\n" + `c</string>
+</resources>`
+
+	root, err := read(doc)
+	if err != nil {
+		t.Fatalf("got %s", err)
+	}
+
+	resources := root.Children[0]
+
+	tests := []struct {
+		node    *Node
+		rawText string
+		attrs   []Attr
+	}{
+		{resources.Children[0], "", []Attr{
+			{Attr: attr("", "name", "quote"), Raw: "quote", Quote: '\''},
+			{Attr: attr("tools", "ignore", "x'y"), Raw: "x&apos;y", Quote: '"'},
+		}},
+		{resources.Children[0].Children[0], "He said &quot;hi&quot;&#8230;&#160;&#x2014;", nil},
+		{resources.Children[1], "", []Attr{
+			{Attr: attr("", "name", "crlf"), Raw: "crlf", Quote: '"'},
+			{Attr: attr("", "value", `a "b"`), Raw: `a "b"`, Quote: '\''},
+		}},
+		{resources.Children[1].Children[0], "a&amp;b\nc", nil},
+	}
+
+	for i, tt := range tests {
+		if tt.node.RawText != tt.rawText {
+			t.Errorf("got raw text %q for node %d, want %q", tt.node.RawText, i, tt.rawText)
+		}
+		if !reflect.DeepEqual(tt.node.Attrs, tt.attrs) {
+			t.Errorf("got attributes %+v for node %d, want %+v", tt.node.Attrs, i, tt.attrs)
+		}
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	doc := `<LinearLayout>
+    <!-- axmlfmt:off -->
+    <View b="1"
+          a="2" />
+
+    <View />
+    <!-- axmlfmt:on -->
+    <View />
+    <!-- axmlfmt:ignore-next -->
+    <FrameLayout><View /></FrameLayout>
+    <View />
+    <LinearLayout>
+        <!--axmlfmt:off-->
+        <View />
+    </LinearLayout>
+</LinearLayout>`
+
+	root, err := read(doc)
+	if err != nil {
+		t.Fatalf("got %s", err)
+	}
+
+	expected := `element LinearLayout
+  comment " axmlfmt:off "
+  verbatim "<View b=\"1\"\n          a=\"2\" />\n\n    <View />"
+  comment " axmlfmt:on "
+  element View
+  comment " axmlfmt:ignore-next "
+  verbatim "<FrameLayout><View /></FrameLayout>"
+  element View
+  element LinearLayout
+    comment "axmlfmt:off"
+    verbatim "<View />"
+`
+	if dump(root) != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", dump(root), expected)
+	}
+}
+
+func read(doc string) (*Node, error) {
+	return ReadXML([]byte(doc))
+}
+
+func attr(space, local, value string) xml.Attr {
+	return xml.Attr{Name: tagName(space, local), Value: value}
 }
 
 func tagName(space, local string) xml.Name {
 	return xml.Name{Space: space, Local: local}
 }
 
-func equal(expected, actual []Element) bool {
-	return reflect.DeepEqual(expected, actual)
+// all returns the nodes in the tree below root in document order
+func all(root *Node) []*Node {
+	nodes := make([]*Node, 0)
+	for _, c := range root.Children {
+		nodes = append(nodes, c)
+		nodes = append(nodes, all(c)...)
+	}
+
+	return nodes
 }
 
-func str(ee []Element) string {
-	s, _ := json.MarshalIndent(ee, "", "  ")
-	return string(s)
+// dump returns a description of the tree below root, with a line for each
+// node which is indented by its depth
+func dump(root *Node) string {
+	b := &strings.Builder{}
+	dumpChildren(b, root, 0)
+
+	return b.String()
+}
+
+func dumpChildren(b *strings.Builder, n *Node, depth int) {
+	for _, c := range n.Children {
+		b.WriteString(strings.Repeat("  ", depth))
+
+		switch c.Type {
+		case ElementNode:
+			fmt.Fprintf(b, "element %s", c.Name.Local)
+			if c.TextContent {
+				b.WriteString(" text")
+			}
+		case TextNode:
+			fmt.Fprintf(b, "text %q", c.Data)
+			if c.IsCDATA {
+				b.WriteString(" cdata")
+			}
+		case CommentNode:
+			fmt.Fprintf(b, "comment %q", c.Data)
+		case ProcInstNode:
+			fmt.Fprintf(b, "procinst %s %q", c.Target, c.Data)
+		case DirectiveNode:
+			fmt.Fprintf(b, "directive %q", c.Data)
+		case VerbatimNode:
+			fmt.Fprintf(b, "verbatim %q", c.Data)
+		}
+
+		b.WriteString("\n")
+		dumpChildren(b, c, depth+1)
+	}
 }
@@ -0,0 +1,67 @@
+package parse
+
+import "strings"
+
+// lineEndings normalizes line endings the same way as the decoder
+var lineEndings = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// rawText returns the source of character data with its line endings
+// normalized
+func rawText(src []byte) string {
+	return lineEndings.Replace(string(src))
+}
+
+// rawAttr is the value of an attribute as it's written in the source
+type rawAttr struct {
+	value []byte
+	quote byte
+}
+
+// scanAttrValues returns the values of the attributes in the start element
+// tag in the order that they appear
+func scanAttrValues(tag []byte) []rawAttr {
+	values := make([]rawAttr, 0)
+
+	i := 0
+	// Skip over "<" and the element name
+	for i < len(tag) && !isSpace(tag[i]) && tag[i] != '>' && tag[i] != '/' {
+		i++
+	}
+
+	for {
+		for i < len(tag) && isSpace(tag[i]) {
+			i++
+		}
+		if i >= len(tag) || tag[i] == '>' || tag[i] == '/' {
+			return values
+		}
+
+		// The attribute name
+		for i < len(tag) && !isSpace(tag[i]) && tag[i] != '=' {
+			i++
+		}
+		for i < len(tag) && (isSpace(tag[i]) || tag[i] == '=') {
+			i++
+		}
+		if i >= len(tag) {
+			return values
+		}
+
+		quote := tag[i]
+		start := i + 1
+		end := start
+		for end < len(tag) && tag[end] != quote {
+			end++
+		}
+		if end >= len(tag) {
+			return values
+		}
+
+		values = append(values, rawAttr{value: tag[start:end], quote: quote})
+		i = end + 1
+	}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
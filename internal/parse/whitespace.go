@@ -0,0 +1,106 @@
+package parse
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// xmlNS is the namespace of the xml prefix, which is used for xml:space
+const xmlNS = "http://www.w3.org/XML/1998/namespace"
+
+// isWhitespace returns whether the character data only consists of XML
+// whitespace. Other Unicode spaces, such as non-breaking spaces, are content.
+func isWhitespace(cd xml.CharData) bool {
+	return len(strings.Trim(string(cd), " \t\r\n")) == 0
+}
+
+// dropInsignificantSpace removes the whitespace-only text which isn't part
+// of the content of a text container from the tree. Whitespace is significant
+// in text containers, such as string resources, elements with text, and
+// elements with xml:space="preserve", as well as the elements nested in them.
+// The new lines in removed whitespace are added to the node which follows it.
+func dropInsignificantSpace(n *Node) {
+	container := isTextContainer(n)
+	n.TextContent = container && len(n.Children) > 0
+
+	kept := n.Children[:0]
+	newLines := 0
+	for _, c := range n.Children {
+		if c.Type == TextNode && !c.IsCDATA && !container && isWhitespace(xml.CharData(c.Data)) {
+			newLines += c.NewLinesBefore + strings.Count(c.Data, "\n")
+			continue
+		}
+
+		c.NewLinesBefore += newLines
+		newLines = 0
+
+		kept = append(kept, c)
+	}
+	n.Children = kept
+
+	for _, c := range n.Children {
+		if c.Type == ElementNode {
+			dropInsignificantSpace(c)
+		}
+	}
+}
+
+// isTextContainer returns whether whitespace is significant in the content of
+// the node. The parent of the node has to have been checked first.
+func isTextContainer(n *Node) bool {
+	if n.Type != ElementNode {
+		return false
+	}
+
+	if n.Parent != nil && n.Parent.TextContent {
+		return true
+	}
+
+	for _, c := range n.Children {
+		if c.Type == TextNode && (c.IsCDATA || !isWhitespace(xml.CharData(c.Data))) {
+			return true
+		}
+	}
+
+	if isSpacePreserved(n) {
+		return true
+	}
+
+	var parentName xml.Name
+	if n.Parent != nil {
+		parentName = n.Parent.Name
+	}
+
+	return isStringResource(n.Name, parentName)
+}
+
+// isSpacePreserved returns whether xml:space="preserve" applies to the node,
+// either from the node itself or the nearest ancestor which sets xml:space
+func isSpacePreserved(n *Node) bool {
+	for ; n != nil; n = n.Parent {
+		for _, a := range n.Attrs {
+			if a.Name.Space == xmlNS && a.Name.Local == "space" {
+				return a.Value == "preserve"
+			}
+		}
+	}
+
+	return false
+}
+
+// isStringResource returns whether an element with the given name and parent
+// holds the text of a string resource, where whitespace is part of the string
+func isStringResource(name, parent xml.Name) bool {
+	if name.Space != "" || parent.Space != "" {
+		return false
+	}
+
+	switch name.Local {
+	case "string":
+		return true
+	case "item":
+		return parent.Local == "plurals" || parent.Local == "string-array"
+	}
+
+	return false
+}
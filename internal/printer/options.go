@@ -0,0 +1,168 @@
+package printer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Options configures how a Printer formats documents
+type Options struct {
+	// Indent is printed once for each level of nesting, e.g. four spaces or
+	// a tab
+	Indent string
+
+	// AttributeIndent is printed after the indent of an element before each
+	// of its attributes which are on their own line. Indent is used when
+	// empty.
+	AttributeIndent string
+
+	// NamespacePrefixes maps namespace URIs to the prefix which is used for
+	// them, in addition to the built-in prefixes for well-known namespaces
+	// such as android, app and tools
+	NamespacePrefixes map[string]string
+
+	// NamespaceOrder is the order of attributes by namespace URI, where
+	// "xmlns" is used for namespace declarations and "" for attributes
+	// without a namespace. The default order is used when nil.
+	NamespaceOrder []string
+
+	// AndroidAttributeOrder lists the names of attributes in the android
+	// namespace, without a prefix, which come before the others. The
+	// default order is used when nil.
+	AndroidAttributeOrder []string
+
+	// AttributeOrder is the list of rules which attributes are ordered by.
+	// It replaces NamespaceOrder and AndroidAttributeOrder when it isn't
+	// nil.
+	AttributeOrder []AttrRule
+
+	// BlankLines determines where blank lines are printed between elements
+	BlankLines BlankLinePolicy
+
+	// MaxBlankLines is the largest number of consecutive blank lines which
+	// are kept from the source with BlankLinesPreserve
+	MaxBlankLines int
+
+	// LineEnding is printed at the end of each line. "\n" is used when
+	// empty.
+	LineEnding string
+
+	// FinalNewline is whether the document ends with a line ending
+	FinalNewline bool
+
+	// Lossless prints character data and attribute values as they're
+	// written in the source, keeping entity and character references, so
+	// that only the layout of the document changes
+	Lossless bool
+}
+
+// DefaultOptions returns the options used when there's no configuration
+func DefaultOptions() Options {
+	return Options{
+		Indent:        "    ",
+		BlankLines:    BlankLinesAlways,
+		MaxBlankLines: 1,
+		LineEnding:    "\n",
+		FinalNewline:  true,
+	}
+}
+
+// AttrRules returns the rules which attributes are ordered by with these
+// options, either AttributeOrder or the rules for NamespaceOrder and
+// AndroidAttributeOrder
+func (o Options) AttrRules() []AttrRule {
+	if o.AttributeOrder != nil {
+		return o.AttributeOrder
+	}
+
+	if o.NamespaceOrder == nil && o.AndroidAttributeOrder == nil {
+		return defaultAttrOrder.rules
+	}
+
+	namespaces := nsPriority
+	if o.NamespaceOrder != nil {
+		namespaces = o.NamespaceOrder
+	}
+	android := androidPriority
+	if o.AndroidAttributeOrder != nil {
+		android = o.AndroidAttributeOrder
+	}
+
+	return orderFromPriorities(namespaces, android).rules
+}
+
+// ParseIndent returns the indent described by s, which is either a number of
+// spaces, "tab", or a number of tabs such as "2tabs". The number can't be 0
+// since an empty indent means that the indent isn't set.
+func ParseIndent(s string) (string, error) {
+	unit := " "
+	count := s
+
+	if strings.HasSuffix(s, "tab") || strings.HasSuffix(s, "tabs") {
+		unit = "\t"
+		count = strings.TrimSuffix(strings.TrimSuffix(s, "s"), "tab")
+		if count == "" {
+			count = "1"
+		}
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return "", fmt.Errorf("invalid indent %q, expected a number of spaces or \"tab\"", s)
+	}
+
+	return strings.Repeat(unit, n), nil
+}
+
+// BlankLinePolicy determines where blank lines are printed between elements
+type BlankLinePolicy int
+
+const (
+	// BlankLinesAlways prints a blank line before each element and comment
+	// which follows an element
+	BlankLinesAlways BlankLinePolicy = iota
+	// BlankLinesNever doesn't print any blank lines
+	BlankLinesNever
+	// BlankLinesPreserve keeps the blank lines before elements and comments
+	// from the source, up to Options.MaxBlankLines of them
+	BlankLinesPreserve
+)
+
+// ParseBlankLinePolicy returns the policy with the given name, "always",
+// "never" or "preserve"
+func ParseBlankLinePolicy(s string) (BlankLinePolicy, error) {
+	switch s {
+	case "always":
+		return BlankLinesAlways, nil
+	case "never":
+		return BlankLinesNever, nil
+	case "preserve":
+		return BlankLinesPreserve, nil
+	default:
+		return 0, fmt.Errorf("unknown blank line policy %q", s)
+	}
+}
+
+// defaultPrefixes are the prefixes used for well-known namespaces
+var defaultPrefixes = map[string]string{
+	"http://schemas.android.com/apk/res/android":  "android",
+	"http://schemas.android.com/apk/res-auto":     "app",
+	"http://schemas.android.com/tools":            "tools",
+	"http://www.w3.org/XML/1998/namespace":        "xml",
+	"http://schemas.android.com/aapt":             "aapt",
+	"http://schemas.android.com/apk/distribution": "dist",
+	"urn:oasis:names:tc:xliff:document:1.2":       "xliff",
+}
+
+// WellKnownNamespace returns the namespace URI which a well-known prefix, such
+// as android, app or tools, is used for
+func WellKnownNamespace(prefix string) (string, bool) {
+	for ns, p := range defaultPrefixes {
+		if p == prefix {
+			return ns, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,32 @@
+package printer
+
+import "testing"
+
+func TestParseIndent(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"4", "    "},
+		{"tab", "\t"},
+		{"2tabs", "\t\t"},
+		{"1tab", "\t"},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseIndent(tt.s)
+		if err != nil {
+			t.Errorf("ParseIndent(%q) failed: %v", tt.s, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseIndent(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+
+	for _, s := range []string{"", "0", "0tabs", "-1", "wide", "tabtab"} {
+		_, err := ParseIndent(s)
+		if err == nil {
+			t.Errorf("ParseIndent(%q) succeeded, want error", s)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package printer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// androidStudioOrder is the default arrangement of Android Studio's XML code
+// style for Android, which puts the attributes in the android namespace in
+// androidAttributeOrder right after the attributes without a namespace
+var androidStudioOrder = []string{
+	"xmlns:android",
+	"xmlns:*",
+	"android:id",
+	"android:name",
+	"name",
+	"style",
+	"*",
+}
+
+// androidAttributeOrder is the order of names in Android Studio's
+// ANDROID_ATTRIBUTE_ORDER, which puts layout_* attributes first
+var androidAttributeOrder = []string{
+	"layout_width",
+	"layout_height",
+	"layout_*",
+	"width",
+	"height",
+	"*",
+}
+
+// attrOrderPresets are the rules of the built-in attribute orders by name
+var attrOrderPresets = map[string][]AttrRule{
+	"axmlfmt":        defaultAttrOrder.rules,
+	"android-studio": androidStudioRules(),
+}
+
+func androidStudioRules() []AttrRule {
+	rules := mustParseAttrRules(androidStudioOrder)
+	rules = append(rules, AndroidAttributeOrder("http://schemas.android.com/apk/res/android")...)
+	return append(rules, AttrRule{Namespace: AnyNamespace, Name: anyName})
+}
+
+// AndroidAttributeOrder returns the rules which order attributes in the given
+// namespace like Android Studio's ANDROID_ATTRIBUTE_ORDER: layout_width,
+// layout_height, the other layout_* attributes, width and height come before
+// the rest
+func AndroidAttributeOrder(namespace string) []AttrRule {
+	rules := make([]AttrRule, len(androidAttributeOrder))
+	for i, name := range androidAttributeOrder {
+		rules[i] = AttrRule{Namespace: namespace, Name: regexp.MustCompile("^(?:" + globPattern(name) + ")$")}
+	}
+
+	return rules
+}
+
+func mustParseAttrRules(rules []string) []AttrRule {
+	parsed, err := ParseAttrRules(rules, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return parsed
+}
+
+// AttrOrderPreset returns the rules of the built-in attribute order with the
+// given name, "axmlfmt" for the default order or "android-studio" for the
+// order of Android Studio's default arrangement
+func AttrOrderPreset(name string) ([]AttrRule, error) {
+	rules, ok := attrOrderPresets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown attribute order preset %q, expected one of %s", name, presetNames())
+	}
+
+	return append([]AttrRule(nil), rules...), nil
+}
+
+func presetNames() string {
+	names := make([]string, 0, len(attrOrderPresets))
+	for name := range attrOrderPresets {
+		names = append(names, fmt.Sprintf("%q", name))
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
@@ -1,98 +1,163 @@
 package printer
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/rsookram/axmlfmt/internal/parse"
 )
 
 type Printer struct {
-	indent string
+	indent       string
+	attrIndent   string
+	prefixes     map[string]string
+	attrOrder    attrOrder
+	blankLines   BlankLinePolicy
+	maxBlank     int
+	lineEnding   string
+	finalNewline bool
+	lossless     bool
 }
 
-func New(indent string) Printer {
+func New(opts Options) Printer {
+	prefixes := make(map[string]string, len(defaultPrefixes)+len(opts.NamespacePrefixes))
+	for ns, prefix := range defaultPrefixes {
+		prefixes[ns] = prefix
+	}
+	for ns, prefix := range opts.NamespacePrefixes {
+		prefixes[ns] = prefix
+	}
+
+	attrIndent := opts.AttributeIndent
+	if attrIndent == "" {
+		attrIndent = opts.Indent
+	}
+
+	lineEnding := opts.LineEnding
+	if lineEnding == "" {
+		lineEnding = "\n"
+	}
+
 	return Printer{
-		indent: indent,
+		indent:       opts.Indent,
+		attrIndent:   attrIndent,
+		prefixes:     prefixes,
+		attrOrder:    attrOrder{rules: opts.AttrRules()},
+		blankLines:   opts.BlankLines,
+		maxBlank:     opts.MaxBlankLines,
+		lineEnding:   lineEnding,
+		finalNewline: opts.FinalNewline,
+		lossless:     opts.Lossless,
 	}
 }
 
-func (p Printer) Fprint(w io.Writer, elements []parse.Element) error {
-	newLinePositions := determineNewLinePositions(elements)
+// Fprint formats the document with the given root, which is a
+// parse.DocumentNode, and writes the result to w. The tree is rearranged in
+// place by sorting attributes and assigning blank lines.
+func (p Printer) Fprint(w io.Writer, root *parse.Node) error {
+	p = p.keepVerbatimPrefixes(root)
+	p = p.keepShadowedPrefixes(root)
+	p.arrange(root)
 
-	for i, ele := range elements {
-		depth := ele.Depth
+	buf := &bytes.Buffer{}
 
-		var err error
-		switch token := ele.Token.(type) {
-		case xml.StartElement:
-			attrs := sortAttrs(token.Attr)
-			err = p.startElement(w, token.Name, attrs, ele.IsSelfClosing, ele.ContainsCharData, depth)
-		case xml.EndElement:
-			err = p.endElement(w, token.Name, ele.ContainsCharData, depth)
-		case xml.CharData:
-			err = p.charData(w, string(token))
-		case xml.Comment:
-			err = p.comment(w, string(token), depth)
-		case xml.ProcInst:
-			err = printProcInst(w, token.Target, string(token.Inst))
+	for _, n := range root.Children {
+		err := p.printNode(buf, n, 0)
+		if err != nil {
+			return err
 		}
+	}
 
-		if newLinePositions[i] {
-			_, err = fmt.Fprintln(w)
-		}
+	out := buf.Bytes()
 
-		if err != nil {
+	if !p.finalNewline {
+		out = bytes.TrimRight(out, "\n")
+	}
+
+	if p.lineEnding != "\n" {
+		out = bytes.ReplaceAll(out, []byte("\n"), []byte(p.lineEnding))
+	}
+
+	_, err := w.Write(out)
+	return err
+}
+
+// printNode prints the node, and its children, which is nested in depth
+// elements
+func (p Printer) printNode(w io.Writer, n *parse.Node, depth int) error {
+	_, err := fmt.Fprint(w, strings.Repeat("\n", n.BlankLinesBefore))
+	if err != nil {
+		return err
+	}
+
+	switch n.Type {
+	case parse.ElementNode:
+		return p.element(w, n, depth)
+	case parse.TextNode:
+		if (p.lossless || n.EntityReferences) && n.RawText != "" {
+			_, err = fmt.Fprint(w, n.RawText)
 			return err
 		}
+		return p.charData(w, n.Data, n.IsCDATA)
+	case parse.CommentNode:
+		return p.comment(w, n.Data, n.IsInline(), depth)
+	case parse.ProcInstNode:
+		return printProcInst(w, n.Target, n.Data)
+	case parse.DirectiveNode:
+		return p.directive(w, n.Data, depth)
+	case parse.VerbatimNode:
+		return p.verbatim(w, n.Data, n.IsInline(), depth)
 	}
 
 	return nil
 }
 
-// determineNewLinePositions returns whether a new line should be printed after
-// the element at a given position
-func determineNewLinePositions(elements []parse.Element) []bool {
-	positions := make([]bool, len(elements))
-
-	for i := 0; i < len(elements)-1; i++ {
-		curr := elements[i].Token
-		next := elements[i+1].Token
+func (p Printer) element(w io.Writer, n *parse.Node, depth int) error {
+	err := p.startElement(w, n, depth)
+	if err != nil {
+		return err
+	}
 
-		switch curr.(type) {
-		case xml.StartElement, xml.EndElement:
-			switch next.(type) {
-			case xml.StartElement, xml.Comment:
-				positions[i] = true
-			}
+	for _, c := range n.Children {
+		err = p.printNode(w, c, depth+1)
+		if err != nil {
+			return err
 		}
 	}
 
-	return positions
+	return p.endElement(w, n, depth)
 }
 
 func isXLIFF(name xml.Name) bool {
 	return name.Space == "urn:oasis:names:tc:xliff:document:1.2" && name.Local == "g"
 }
 
-func (p Printer) startElement(w io.Writer, name xml.Name, attrs []xml.Attr, isSelfClosing, containsCharData bool, depth int) error {
-	_, err := fmt.Fprint(w, duplicate(p.indent, depth))
-	if err != nil {
-		return err
-	}
+func (p Printer) startElement(w io.Writer, n *parse.Node, depth int) error {
+	inline := n.IsInline()
+	isSelfClosing := len(n.Children) == 0
+	containsCharData := n.TextContent
+	tagName := p.elementName(n.Name, n.Namespaces)
+	useRaw := p.lossless || n.EntityReferences
 
-	ns := tagNamespace(name)
-	tagName := name.Local
-	if ns != "" {
-		tagName = ns + ":" + tagName
+	if !inline {
+		_, err := fmt.Fprint(w, duplicate(p.indent, depth))
+		if err != nil {
+			return err
+		}
 	}
 
+	attrs, attrNames := p.attrNames(n.Attrs, n.Namespaces)
+
+	var err error
+
 	// Elements without attrs look like `<requestFocus />` or `<resources>`
 	// and elements with one attr look like
 	// `<string name="app_name">` or `<menu xmlns:android="...">`
 	hasAttrs := len(attrs) == 0
-	isSingleLine := len(attrs) == 1 || containsCharData
+	isSingleLine := len(attrs) == 1 || containsCharData || inline
 	if hasAttrs {
 		_, err = fmt.Fprintf(w, "<%s", tagName)
 	} else {
@@ -106,12 +171,12 @@ func (p Printer) startElement(w io.Writer, name xml.Name, attrs []xml.Attr, isSe
 		return err
 	}
 
-	attrIndent := duplicate(p.indent, depth+1)
+	attrIndent := duplicate(p.indent, depth) + p.attrIndent
 	for i, a := range attrs {
 		if isSingleLine {
-			_, err = fmt.Fprintf(w, " %s=\"%s\"", cleanAttrName(a), a.Value)
+			_, err = fmt.Fprintf(w, " %s=%s", attrNames[i], p.attrValue(a, useRaw))
 		} else {
-			_, err = fmt.Fprintf(w, "%s%s=\"%s\"", attrIndent, cleanAttrName(a), a.Value)
+			_, err = fmt.Fprintf(w, "%s%s=%s", attrIndent, attrNames[i], p.attrValue(a, useRaw))
 		}
 
 		// The last attribute is on the same line as the ">"
@@ -128,6 +193,8 @@ func (p Printer) startElement(w io.Writer, name xml.Name, attrs []xml.Attr, isSe
 		_, err = fmt.Fprintf(w, ">")
 	} else if !isSelfClosing {
 		_, err = fmt.Fprintf(w, ">\n")
+	} else if inline {
+		_, err = fmt.Fprintf(w, " />")
 	} else {
 		_, err = fmt.Fprintf(w, " />\n")
 	}
@@ -135,8 +202,16 @@ func (p Printer) startElement(w io.Writer, name xml.Name, attrs []xml.Attr, isSe
 	return err
 }
 
-func (p Printer) endElement(w io.Writer, name xml.Name, containsCharData bool, depth int) error {
-	if !containsCharData {
+func (p Printer) endElement(w io.Writer, n *parse.Node, depth int) error {
+	// Elements without children are self-closing
+	if len(n.Children) == 0 {
+		return nil
+	}
+
+	inline := n.IsInline()
+	tagName := p.elementName(n.Name, n.Namespaces)
+
+	if !n.TextContent && !inline {
 		_, err := fmt.Fprint(w, duplicate(p.indent, depth))
 		if err != nil {
 			return err
@@ -144,20 +219,42 @@ func (p Printer) endElement(w io.Writer, name xml.Name, containsCharData bool, d
 	}
 
 	var err error
-	ns := tagNamespace(name)
-	if ns != "" {
-		_, err = fmt.Fprintf(w, "</%s:%s>", ns, name.Local)
+	if inline {
+		_, err = fmt.Fprintf(w, "</%s>", tagName)
 	} else {
-		_, err = fmt.Fprintf(w, "</%s>\n", name.Local)
+		_, err = fmt.Fprintf(w, "</%s>\n", tagName)
 	}
 	return err
 }
 
-func (p Printer) charData(w io.Writer, value string) error {
-	return xml.EscapeText(w, []byte(value))
+// charData prints character data, keeping CDATA sections from the source
+// as-is since they're often used for HTML in string resources
+func (p Printer) charData(w io.Writer, value string, isCDATA bool) error {
+	if isCDATA {
+		_, err := fmt.Fprintf(w, "<![CDATA[%s]]>", value)
+		return err
+	}
+
+	_, err := fmt.Fprint(w, textEscaper.Replace(value))
+	return err
 }
 
-func (p Printer) comment(w io.Writer, body string, depth int) error {
+// textEscaper escapes the characters which can't appear as-is in character
+// data. Unlike xml.EscapeText, new lines and tabs are left as-is since they
+// can be significant whitespace.
+var textEscaper = strings.NewReplacer(
+	`&`, "&amp;",
+	`<`, "&lt;",
+	`>`, "&gt;",
+	"\r", "&#xD;",
+)
+
+func (p Printer) comment(w io.Writer, body string, inline bool, depth int) error {
+	if inline {
+		_, err := fmt.Fprintf(w, "<!--%s-->", body)
+		return err
+	}
+
 	_, err := fmt.Fprint(w, duplicate(p.indent, depth))
 	if err != nil {
 		return err
@@ -167,55 +264,151 @@ func (p Printer) comment(w io.Writer, body string, depth int) error {
 	return err
 }
 
+// directive prints a directive, such as a DOCTYPE declaration, as-is
+func (p Printer) directive(w io.Writer, body string, depth int) error {
+	_, err := fmt.Fprint(w, duplicate(p.indent, depth))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "<!%s>\n", body)
+	return err
+}
+
+// verbatim prints the source of a part of the document which formatting is
+// turned off for. Only its first line is indented.
+func (p Printer) verbatim(w io.Writer, source string, inline bool, depth int) error {
+	if inline {
+		_, err := fmt.Fprint(w, source)
+		return err
+	}
+
+	_, err := fmt.Fprint(w, duplicate(p.indent, depth))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", source)
+	return err
+}
+
 func printProcInst(w io.Writer, target string, inst string) error {
 	_, err := fmt.Fprintf(w, "<?%s %s?>\n", target, inst)
 	return err
 }
 
-func cleanAttrName(a xml.Attr) string {
+// attrEscaper escapes the characters which can't appear as-is in an attribute
+// value delimited by double quotes. Whitespace other than spaces is escaped
+// since parsers normalize it to spaces.
+var attrEscaper = strings.NewReplacer(
+	`&`, "&amp;",
+	`<`, "&lt;",
+	`"`, "&quot;",
+	"\t", "&#x9;",
+	"\n", "&#xA;",
+	"\r", "&#xD;",
+)
+
+func escapeAttr(value string) string {
+	return attrEscaper.Replace(value)
+}
+
+// attrValue returns the quoted value to print for an attribute. With useRaw,
+// the value from the source is used, with single quotes if it contains
+// double quotes.
+func (p Printer) attrValue(a parse.Attr, useRaw bool) string {
+	if !useRaw || a.Quote == 0 {
+		return `"` + escapeAttr(a.Value) + `"`
+	}
+
+	if strings.Contains(a.Raw, `"`) {
+		return "'" + a.Raw + "'"
+	}
+
+	return `"` + a.Raw + `"`
+}
+
+// attrNames returns the names to print for the given attributes. Namespace
+// declarations which become duplicates once their prefix is standardized are
+// removed.
+func (p Printer) attrNames(attrs []parse.Attr, scope map[string]string) ([]parse.Attr, []string) {
+	kept := make([]parse.Attr, 0, len(attrs))
+	names := make([]string, 0, len(attrs))
+
+	seen := make(map[string]bool, len(attrs))
+	for _, a := range attrs {
+		name := p.cleanAttrName(a.Attr, scope)
+
+		if a.Name.Space == "xmlns" {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+		}
+
+		kept = append(kept, a)
+		names = append(names, name)
+	}
+
+	return kept, names
+}
+
+func (p Printer) cleanAttrName(a xml.Attr, scope map[string]string) string {
 	space := a.Name.Space
 	if space == "" {
 		// Attributes not in a namespace such as style
 		return a.Name.Local
 	}
 
-	space = standardizeNamespace(space)
-
-	name := a.Name.Local
 	if space == "xmlns" {
-		name = standardizeNamespace(a.Value)
-		if name == "" {
-			name = a.Name.Local
+		return "xmlns:" + p.standardizePrefix(a.Value, a.Name.Local, scope)
+	}
+
+	return p.resolvePrefix(space, scope) + ":" + a.Name.Local
+}
+
+// resolvePrefix returns the prefix to print for the namespace URI ns given the
+// namespaces in scope
+func (p Printer) resolvePrefix(ns string, scope map[string]string) string {
+	declared := ""
+	for prefix, uri := range scope {
+		if uri == ns && prefix != "" && (declared == "" || prefix < declared) {
+			declared = prefix
 		}
 	}
 
-	return fmt.Sprintf("%s:%s", space, name)
+	if declared == "" {
+		// The decoder leaves the prefix as-is when it isn't declared
+		declared = ns
+	}
+
+	return p.standardizePrefix(ns, declared, scope)
 }
 
-func standardizeNamespace(ns string) string {
-	switch ns {
-	case "http://schemas.android.com/apk/res/android":
-		return "android"
-	case "http://schemas.android.com/apk/res-auto":
-		return "app"
-	case "http://schemas.android.com/tools":
-		return "tools"
-	case "xmlns":
-		return "xmlns"
-	default:
-		return ""
+// standardizePrefix returns the prefix to print for the namespace URI ns,
+// which is declared with the given prefix. Well-known namespaces use their
+// standard prefix, unless it's bound to a different namespace.
+func (p Printer) standardizePrefix(ns, declared string, scope map[string]string) string {
+	prefix, ok := p.prefixes[ns]
+	if !ok {
+		return declared
+	}
+
+	if bound, ok := scope[prefix]; ok && bound != ns {
+		return declared
 	}
+
+	return prefix
 }
 
-func tagNamespace(n xml.Name) string {
-	switch n.Space {
-	case "urn:oasis:names:tc:xliff:document:1.2":
-		return "xliff"
-	case "http://schemas.android.com/aapt":
-		return "aapt"
-	default:
-		return ""
+// elementName returns the name to print for an element given the namespaces
+// in scope
+func (p Printer) elementName(name xml.Name, scope map[string]string) string {
+	if name.Space == "" || name.Space == scope[""] {
+		return name.Local
 	}
+
+	return p.resolvePrefix(name.Space, scope) + ":" + name.Local
 }
 
 func duplicate(s string, n int) string {
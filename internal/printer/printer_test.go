@@ -11,26 +11,13 @@ import (
 const indent = "    "
 
 func TestStartElement(t *testing.T) {
-	p := New(indent)
+	p := New(DefaultOptions())
 
 	{
 		w := &strings.Builder{}
-		ee := []parse.Element{
-			{
-				Token: xml.StartElement{
-					Name: xml.Name{
-						Space: "",
-						Local: "resources",
-					},
-					Attr: []xml.Attr{},
-				},
-				Depth:            0,
-				IsSelfClosing:    false,
-				ContainsCharData: false,
-			},
-		}
+		n := element(xml.Name{Space: "", Local: "resources"}, nil, comment("child"))
 
-		err := p.Fprint(w, ee)
+		err := p.startElement(w, n, 0)
 		requireNoError(t, err)
 
 		expected := "<resources>\n"
@@ -41,28 +28,23 @@ func TestStartElement(t *testing.T) {
 }
 
 func TestStartXLIFF(t *testing.T) {
-	p := New(indent)
+	p := New(DefaultOptions())
 
 	w := &strings.Builder{}
-	ee := []parse.Element{
-		{
-			Token: xml.StartElement{
-				Name: xml.Name{
-					Space: "urn:oasis:names:tc:xliff:document:1.2",
-					Local: "g",
-				},
-				Attr: []xml.Attr{
-					{Name: xml.Name{Space: "", Local: "example"}, Value: "2"},
-					{Name: xml.Name{Space: "", Local: "id"}, Value: "quantity"},
-				},
-			},
-			Depth:            1,
-			IsSelfClosing:    false,
-			ContainsCharData: true,
+	n := element(
+		xml.Name{
+			Space: "urn:oasis:names:tc:xliff:document:1.2",
+			Local: "g",
 		},
-	}
+		[]xml.Attr{
+			{Name: xml.Name{Space: "", Local: "example"}, Value: "2"},
+			{Name: xml.Name{Space: "", Local: "id"}, Value: "quantity"},
+		},
+		text("%d"),
+	)
+	n.TextContent = true
 
-	err := p.Fprint(w, ee)
+	err := p.startElement(w, n, 1)
 	requireNoError(t, err)
 
 	expected := indent + `<xliff:g example="2" id="quantity">`
@@ -71,28 +53,52 @@ func TestStartXLIFF(t *testing.T) {
 	}
 }
 
-func TestStartAAPT(t *testing.T) {
-	p := New(indent)
+func TestStartEmptyXLIFF(t *testing.T) {
+	p := New(DefaultOptions())
 
 	w := &strings.Builder{}
-	ee := []parse.Element{
-		{
-			Token: xml.StartElement{
-				Name: xml.Name{
-					Space: "http://schemas.android.com/aapt",
-					Local: "attr",
-				},
-				Attr: []xml.Attr{
-					{Name: xml.Name{Space: "", Local: "name"}, Value: "android:fillColor"},
-				},
-			},
-			Depth:            2,
-			IsSelfClosing:    false,
-			ContainsCharData: false,
+	g := element(
+		xml.Name{
+			Space: "urn:oasis:names:tc:xliff:document:1.2",
+			Local: "g",
+		},
+		[]xml.Attr{
+			{Name: xml.Name{Space: "", Local: "id"}, Value: "a"},
+			{Name: xml.Name{Space: "", Local: "example"}, Value: "b"},
 		},
+	)
+	str := element(
+		xml.Name{Space: "", Local: "string"},
+		[]xml.Attr{{Name: xml.Name{Space: "", Local: "name"}, Value: "s"}},
+		text("Hi "), g, text(" there"),
+	)
+	str.TextContent = true
+
+	err := p.Fprint(w, document(str))
+	requireNoError(t, err)
+
+	expected := `<string name="s">Hi <xliff:g example="b" id="a" /> there</string>` + "\n"
+	if w.String() != expected {
+		t.Errorf("got: %s, want %s", w.String(), expected)
 	}
+}
+
+func TestStartAAPT(t *testing.T) {
+	p := New(DefaultOptions())
 
-	err := p.Fprint(w, ee)
+	w := &strings.Builder{}
+	n := element(
+		xml.Name{
+			Space: "http://schemas.android.com/aapt",
+			Local: "attr",
+		},
+		[]xml.Attr{
+			{Name: xml.Name{Space: "", Local: "name"}, Value: "android:fillColor"},
+		},
+		element(xml.Name{Space: "", Local: "gradient"}, nil),
+	)
+
+	err := p.startElement(w, n, 2)
 	requireNoError(t, err)
 
 	expected := indent + indent + `<aapt:attr name="android:fillColor">` + "\n"
@@ -102,31 +108,25 @@ func TestStartAAPT(t *testing.T) {
 }
 
 func TestStandardizeNamespaceName(t *testing.T) {
-	p := New(indent)
+	p := New(DefaultOptions())
 
 	w := &strings.Builder{}
-	ee := []parse.Element{
-		{
-			Token: xml.StartElement{
-				Name: xml.Name{
-					Space: "",
-					Local: "androidx.cardview.widget.CardView",
-				},
-				Attr: []xml.Attr{
-					{Name: xml.Name{Space: "xmlns", Local: "android"}, Value: "http://schemas.android.com/apk/res/android"},
-					{Name: xml.Name{Space: "xmlns", Local: "card_view"}, Value: "http://schemas.android.com/apk/res-auto"},
-					{Name: xml.Name{Space: "http://schemas.android.com/apk/res/android", Local: "layout_width"}, Value: "match_parent"},
-					{Name: xml.Name{Space: "http://schemas.android.com/apk/res/android", Local: "layout_height"}, Value: "wrap_content"},
-					{Name: xml.Name{Space: "http://schemas.android.com/apk/res-auto", Local: "cardCornerRadius"}, Value: "4dp"},
-				},
-			},
-			Depth:            0,
-			IsSelfClosing:    false,
-			ContainsCharData: false,
+	n := element(
+		xml.Name{
+			Space: "",
+			Local: "androidx.cardview.widget.CardView",
 		},
-	}
+		[]xml.Attr{
+			{Name: xml.Name{Space: "xmlns", Local: "android"}, Value: "http://schemas.android.com/apk/res/android"},
+			{Name: xml.Name{Space: "xmlns", Local: "card_view"}, Value: "http://schemas.android.com/apk/res-auto"},
+			{Name: xml.Name{Space: "http://schemas.android.com/apk/res/android", Local: "layout_width"}, Value: "match_parent"},
+			{Name: xml.Name{Space: "http://schemas.android.com/apk/res/android", Local: "layout_height"}, Value: "wrap_content"},
+			{Name: xml.Name{Space: "http://schemas.android.com/apk/res-auto", Local: "cardCornerRadius"}, Value: "4dp"},
+		},
+		element(xml.Name{Space: "", Local: "TextView"}, nil),
+	)
 
-	err := p.Fprint(w, ee)
+	err := p.startElement(w, n, 0)
 	requireNoError(t, err)
 
 	expected := `<androidx.cardview.widget.CardView
@@ -141,25 +141,172 @@ func TestStandardizeNamespaceName(t *testing.T) {
 	}
 }
 
-func TestEndElement(t *testing.T) {
-	p := New(indent)
+func TestAttributeIndent(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Indent = "\t"
+	opts.AttributeIndent = "\t\t"
+	p := New(opts)
 
 	w := &strings.Builder{}
-	ee := []parse.Element{
-		{
-			Token: xml.EndElement{
-				Name: xml.Name{
-					Space: "",
-					Local: "androidx.constraintlayout.widget.ConstraintLayout",
-				},
-			},
-			Depth:            1,
-			IsSelfClosing:    false,
-			ContainsCharData: false,
+	n := element(
+		xml.Name{Space: "", Local: "View"},
+		[]xml.Attr{
+			{Name: xml.Name{Space: "http://schemas.android.com/apk/res/android", Local: "layout_width"}, Value: "match_parent"},
+			{Name: xml.Name{Space: "http://schemas.android.com/apk/res/android", Local: "layout_height"}, Value: "1dp"},
+		},
+	)
+
+	err := p.startElement(w, n, 1)
+	requireNoError(t, err)
+
+	expected := "\t<View\n" +
+		"\t\t\tandroid:layout_width=\"match_parent\"\n" +
+		"\t\t\tandroid:layout_height=\"1dp\" />\n"
+	if w.String() != expected {
+		t.Errorf("got: %q, want %q", w.String(), expected)
+	}
+}
+
+func TestAttributeEscaping(t *testing.T) {
+	p := New(DefaultOptions())
+
+	attrs := []xml.Attr{
+		{Name: xml.Name{Space: "http://schemas.android.com/apk/res/android", Local: "text"}, Value: `Say "hi" & <bye>`},
+		{Name: xml.Name{Space: "http://schemas.android.com/apk/res/android", Local: "visibility"}, Value: "@{a && b ? View.VISIBLE : View.GONE}"},
+		{Name: xml.Name{Space: "http://schemas.android.com/tools", Local: "text"}, Value: "'single'\tand\nlines\r"},
+	}
+
+	w := &strings.Builder{}
+	root := document(element(xml.Name{Space: "", Local: "TextView"}, attrs))
+
+	err := p.Fprint(w, root)
+	requireNoError(t, err)
+
+	expected := `<TextView
+    android:text="Say &quot;hi&quot; &amp; &lt;bye>"
+    android:visibility="@{a &amp;&amp; b ? View.VISIBLE : View.GONE}"
+    tools:text="'single'&#x9;and&#xA;lines&#xD;" />
+`
+	if w.String() != expected {
+		t.Errorf("got: %s, want %s", w.String(), expected)
+	}
+
+	doc := `<TextView
+    xmlns:android="http://schemas.android.com/apk/res/android"
+    xmlns:tools="http://schemas.android.com/tools"` + w.String()[len("<TextView"):]
+	parsed, err := parse.ReadXML([]byte(doc))
+	requireNoError(t, err)
+
+	roundTripped := parsed.Children[0].Attrs[2:]
+	for i, a := range roundTripped {
+		if a.Attr != attrs[i] {
+			t.Errorf("got %+v after round trip, want %+v", a.Attr, attrs[i])
+		}
+	}
+}
+
+func TestCustomNamespace(t *testing.T) {
+	p := New(DefaultOptions())
+
+	w := &strings.Builder{}
+	n := element(
+		xml.Name{Space: "", Local: "layout"},
+		[]xml.Attr{
+			{Name: xml.Name{Space: "xmlns", Local: "bind"}, Value: "http://example.com/bind"},
+			{Name: xml.Name{Space: "xmlns", Local: "app"}, Value: "http://example.com/app"},
+			{Name: xml.Name{Space: "xmlns", Local: "card_view"}, Value: "http://schemas.android.com/apk/res-auto"},
+			{Name: xml.Name{Space: "http://example.com/bind", Local: "visible"}, Value: "true"},
+			{Name: xml.Name{Space: "http://example.com/app", Local: "title"}, Value: "x"},
+			{Name: xml.Name{Space: "http://schemas.android.com/apk/res-auto", Local: "cardCornerRadius"}, Value: "4dp"},
+			{Name: xml.Name{Space: "undeclared", Local: "attr"}, Value: "y"},
+		},
+	)
+	n.Namespaces = map[string]string{
+		"bind":      "http://example.com/bind",
+		"app":       "http://example.com/app",
+		"card_view": "http://schemas.android.com/apk/res-auto",
+	}
+
+	err := p.Fprint(w, document(n))
+	requireNoError(t, err)
+
+	expected := `<layout
+    xmlns:app="http://example.com/app"
+    xmlns:bind="http://example.com/bind"
+    xmlns:card_view="http://schemas.android.com/apk/res-auto"
+    card_view:cardCornerRadius="4dp"
+    undeclared:attr="y"
+    app:title="x"
+    bind:visible="true" />
+`
+	if w.String() != expected {
+		t.Errorf("got: %s, want %s", w.String(), expected)
+	}
+}
+
+func TestDuplicateNamespaceDeclaration(t *testing.T) {
+	p := New(DefaultOptions())
+
+	w := &strings.Builder{}
+	n := element(
+		xml.Name{Space: "", Local: "FrameLayout"},
+		[]xml.Attr{
+			{Name: xml.Name{Space: "xmlns", Local: "app"}, Value: "http://schemas.android.com/apk/res-auto"},
+			{Name: xml.Name{Space: "xmlns", Local: "card_view"}, Value: "http://schemas.android.com/apk/res-auto"},
 		},
+	)
+	n.Namespaces = map[string]string{
+		"app":       "http://schemas.android.com/apk/res-auto",
+		"card_view": "http://schemas.android.com/apk/res-auto",
+	}
+
+	err := p.Fprint(w, document(n))
+	requireNoError(t, err)
+
+	expected := `<FrameLayout xmlns:app="http://schemas.android.com/apk/res-auto" />
+`
+	if w.String() != expected {
+		t.Errorf("got: %s, want %s", w.String(), expected)
 	}
+}
 
-	err := p.Fprint(w, ee)
+func TestShadowedStandardPrefix(t *testing.T) {
+	doc := `<FrameLayout xmlns:card_view="http://schemas.android.com/apk/res-auto"><Inner xmlns:app="http://example.com/other" card_view:x="1"/></FrameLayout>`
+
+	root, err := parse.ReadXML([]byte(doc))
+	requireNoError(t, err)
+
+	w := &strings.Builder{}
+	err = New(DefaultOptions()).Fprint(w, root)
+	requireNoError(t, err)
+
+	// app is bound to another namespace below, so card_view is kept
+	expected := `<FrameLayout xmlns:card_view="http://schemas.android.com/apk/res-auto">
+
+    <Inner
+        xmlns:app="http://example.com/other"
+        card_view:x="1" />
+</FrameLayout>
+`
+	if w.String() != expected {
+		t.Errorf("got: %s, want %s", w.String(), expected)
+	}
+}
+
+func TestEndElement(t *testing.T) {
+	p := New(DefaultOptions())
+
+	w := &strings.Builder{}
+	n := element(
+		xml.Name{
+			Space: "",
+			Local: "androidx.constraintlayout.widget.ConstraintLayout",
+		},
+		nil,
+		element(xml.Name{Space: "", Local: "View"}, nil),
+	)
+
+	err := p.endElement(w, n, 1)
 	requireNoError(t, err)
 
 	expected := indent + "</androidx.constraintlayout.widget.ConstraintLayout>\n"
@@ -169,74 +316,312 @@ func TestEndElement(t *testing.T) {
 }
 
 func TestEndXLIFF(t *testing.T) {
-	p := New(indent)
+	p := New(DefaultOptions())
 
 	w := &strings.Builder{}
-	ee := []parse.Element{
-		{
-			Token: xml.EndElement{
-				Name: xml.Name{
-					Space: "urn:oasis:names:tc:xliff:document:1.2",
-					Local: "g",
-				},
-			},
-			Depth:            2,
-			IsSelfClosing:    false,
-			ContainsCharData: true,
+	g := element(
+		xml.Name{
+			Space: "urn:oasis:names:tc:xliff:document:1.2",
+			Local: "g",
 		},
+		nil,
+		text("%d"),
+	)
+	g.TextContent = true
+	str := element(xml.Name{Space: "", Local: "string"}, nil, g)
+	str.TextContent = true
+
+	err := p.Fprint(w, document(str))
+	requireNoError(t, err)
+
+	expected := "<string><xliff:g>%d</xliff:g></string>\n"
+	if w.String() != expected {
+		t.Errorf("got: %s, want %s", w.String(), expected)
 	}
+}
+
+func TestEndAAPT(t *testing.T) {
+	p := New(DefaultOptions())
 
-	err := p.Fprint(w, ee)
+	w := &strings.Builder{}
+	n := element(
+		xml.Name{
+			Space: "http://schemas.android.com/aapt",
+			Local: "attr",
+		},
+		nil,
+		element(xml.Name{Space: "", Local: "gradient"}, nil),
+	)
+
+	err := p.endElement(w, n, 2)
 	requireNoError(t, err)
 
-	expected := "</xliff:g>"
+	expected := indent + indent + "</aapt:attr>\n"
 	if w.String() != expected {
 		t.Errorf("got: %s, want %s", w.String(), expected)
 	}
 }
 
-func TestEndAAPT(t *testing.T) {
-	p := New(indent)
+func TestNamespacedElements(t *testing.T) {
+	doc := `<manifest
+    xmlns:android="http://schemas.android.com/apk/res/android"
+    xmlns:dist="http://schemas.android.com/apk/distribution"
+    xmlns:lib="http://example.com/lib">
+
+    <dist:module dist:instant="false">
+
+        <dist:delivery>
+
+            <dist:on-demand />
+        </dist:delivery>
+    </dist:module>
+
+    <lib:config>
+
+        <lib:option />
+    </lib:config>
+
+    <application />
+</manifest>
+`
+
+	ee, err := parse.ReadXML([]byte(doc))
+	requireNoError(t, err)
 
 	w := &strings.Builder{}
-	ee := []parse.Element{
+	err = New(DefaultOptions()).Fprint(w, ee)
+	requireNoError(t, err)
+
+	if w.String() != doc {
+		t.Errorf("got: %s, want %s", w.String(), doc)
+	}
+}
+
+func TestBlankLines(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="utf-8"?>
+
+<resources>
+    <string name="a">A</string>
+    <string name="b">B</string>
+
+
+
+    <!-- Section -->
+
+    <string name="c">C</string>
+</resources>
+`
+
+	tests := []struct {
+		policy   BlankLinePolicy
+		max      int
+		expected string
+	}{
+		{
+			BlankLinesAlways,
+			1,
+			`<?xml version="1.0" encoding="utf-8"?>
+<resources>
+
+    <string name="a">A</string>
+
+    <string name="b">B</string>
+
+    <!-- Section -->
+    <string name="c">C</string>
+</resources>
+`,
+		},
+		{
+			BlankLinesNever,
+			1,
+			`<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <string name="a">A</string>
+    <string name="b">B</string>
+    <!-- Section -->
+    <string name="c">C</string>
+</resources>
+`,
+		},
 		{
-			Token: xml.EndElement{
-				Name: xml.Name{
-					Space: "http://schemas.android.com/aapt",
-					Local: "attr",
-				},
-			},
-			Depth:            2,
-			IsSelfClosing:    false,
-			ContainsCharData: false,
+			BlankLinesPreserve,
+			1,
+			`<?xml version="1.0" encoding="utf-8"?>
+
+<resources>
+    <string name="a">A</string>
+    <string name="b">B</string>
+
+    <!-- Section -->
+
+    <string name="c">C</string>
+</resources>
+`,
+		},
+		{
+			BlankLinesPreserve,
+			2,
+			`<?xml version="1.0" encoding="utf-8"?>
+
+<resources>
+    <string name="a">A</string>
+    <string name="b">B</string>
+
+
+    <!-- Section -->
+
+    <string name="c">C</string>
+</resources>
+`,
 		},
 	}
 
-	err := p.Fprint(w, ee)
+	ee, err := parse.ReadXML([]byte(doc))
+	requireNoError(t, err)
+
+	for _, tt := range tests {
+		opts := DefaultOptions()
+		opts.BlankLines = tt.policy
+		opts.MaxBlankLines = tt.max
+
+		w := &strings.Builder{}
+		err = New(opts).Fprint(w, ee)
+		requireNoError(t, err)
+
+		if w.String() != tt.expected {
+			t.Errorf("got: %s, want %s", w.String(), tt.expected)
+		}
+	}
+}
+
+func TestMixedContentWhitespace(t *testing.T) {
+	doc := `<resources>
+<string name="tap"><b>Tap</b> <i>here</i> <!-- now --> now</string>
+<string name="empty"><br/></string>
+<plurals name="items">
+<item quantity="one"> <xliff:g id="count" xmlns:xliff="urn:oasis:names:tc:xliff:document:1.2">%d</xliff:g> item</item>
+</plurals>
+</resources>
+`
+
+	ee, err := parse.ReadXML([]byte(doc))
+	requireNoError(t, err)
+
+	w := &strings.Builder{}
+	err = New(DefaultOptions()).Fprint(w, ee)
+	requireNoError(t, err)
+
+	expected := `<resources>
+
+    <string name="tap"><b>Tap</b> <i>here</i> <!-- now --> now</string>
+
+    <string name="empty"><br /></string>
+
+    <plurals name="items">
+
+        <item quantity="one"> <xliff:g xmlns:xliff="urn:oasis:names:tc:xliff:document:1.2" id="count">%d</xliff:g> item</item>
+    </plurals>
+</resources>
+`
+	if w.String() != expected {
+		t.Errorf("got: %s, want %s", w.String(), expected)
+	}
+}
+
+func TestDirective(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="utf-8"?>
+<!DOCTYPE resources [
+    <!-- Shared names -->
+    <!ENTITY app "MyApp">
+]>
+<resources>
+<string name="welcome" label='&app;'>Welcome to &app;&#8230;</string>
+<string name="plain">Plain &#38; simple</string>
+</resources>
+`
+
+	ee, err := parse.ReadXML([]byte(doc))
 	requireNoError(t, err)
 
-	expected := indent + indent + "</aapt:attr>"
+	w := &strings.Builder{}
+	err = New(DefaultOptions()).Fprint(w, ee)
+	requireNoError(t, err)
+
+	expected := `<?xml version="1.0" encoding="utf-8"?>
+<!DOCTYPE resources [
+    <!-- Shared names -->
+    <!ENTITY app "MyApp">
+]>
+<resources>
+
+    <string label="&app;" name="welcome">Welcome to &app;&#8230;</string>
+
+    <string name="plain">Plain &amp; simple</string>
+</resources>
+`
 	if w.String() != expected {
 		t.Errorf("got: %s, want %s", w.String(), expected)
 	}
 }
 
+func TestLossless(t *testing.T) {
+	doc := `<resources>
+  <string name="ellipsis" tools:ignore='Typo'>Loading&#8230;&#160;&#x2014; don&apos;t</string>
+  <string name='html' format="a&amp;b" value='say "hi"'>&lt;b&gt;</string>
+</resources>
+`
+
+	ee, err := parse.ReadXML([]byte(doc))
+	requireNoError(t, err)
+
+	tests := []struct {
+		lossless bool
+		expected string
+	}{
+		{
+			false,
+			`<resources>
+
+    <string name="ellipsis" tools:ignore="Typo">Loading…` + "\u00a0" + `— don't</string>
+
+    <string format="a&amp;b" name="html" value="say &quot;hi&quot;">&lt;b&gt;</string>
+</resources>
+`,
+		},
+		{
+			true,
+			`<resources>
+
+    <string name="ellipsis" tools:ignore="Typo">Loading&#8230;&#160;&#x2014; don&apos;t</string>
+
+    <string format="a&amp;b" name="html" value='say "hi"'>&lt;b&gt;</string>
+</resources>
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		opts := DefaultOptions()
+		opts.Lossless = tt.lossless
+
+		w := &strings.Builder{}
+		err = New(opts).Fprint(w, ee)
+		requireNoError(t, err)
+
+		if w.String() != tt.expected {
+			t.Errorf("got: %s, want %s", w.String(), tt.expected)
+		}
+	}
+}
+
 func TestCharData(t *testing.T) {
-	p := New(indent)
+	p := New(DefaultOptions())
 
 	{
 		w := &strings.Builder{}
-		ee := []parse.Element{
-			{
-				Token:            xml.CharData("a string"),
-				Depth:            1,
-				IsSelfClosing:    false,
-				ContainsCharData: false,
-			},
-		}
 
-		err := p.Fprint(w, ee)
+		err := p.printNode(w, text("a string"), 1)
 		requireNoError(t, err)
 
 		expected := "a string"
@@ -247,16 +632,8 @@ func TestCharData(t *testing.T) {
 
 	{
 		w := &strings.Builder{}
-		ee := []parse.Element{
-			{
-				Token:            xml.CharData("<b> & </b>"),
-				Depth:            1,
-				IsSelfClosing:    false,
-				ContainsCharData: false,
-			},
-		}
 
-		err := p.Fprint(w, ee)
+		err := p.printNode(w, text("<b> & </b>"), 1)
 		requireNoError(t, err)
 
 		expected := "&lt;b&gt; &amp; &lt;/b&gt;"
@@ -264,23 +641,58 @@ func TestCharData(t *testing.T) {
 			t.Errorf("got: %s, want %s", w.String(), expected)
 		}
 	}
+
+	{
+		w := &strings.Builder{}
+		n := text("<b> & </b>")
+		n.IsCDATA = true
+
+		err := p.printNode(w, n, 1)
+		requireNoError(t, err)
+
+		expected := "<![CDATA[<b> & </b>]]>"
+		if w.String() != expected {
+			t.Errorf("got: %s, want %s", w.String(), expected)
+		}
+	}
+}
+
+func TestCDATA(t *testing.T) {
+	doc := `<resources>
+    <string name="html"><![CDATA[<b>Bold</b> &amp; <i>italic</i>]]></string>
+    <string name="mixed">Hello, <![CDATA[<b>%s</b>]]>!</string>
+    <string name="space"><![CDATA[ ]]></string>
+</resources>
+`
+
+	ee, err := parse.ReadXML([]byte(doc))
+	requireNoError(t, err)
+
+	w := &strings.Builder{}
+	err = New(DefaultOptions()).Fprint(w, ee)
+	requireNoError(t, err)
+
+	expected := `<resources>
+
+    <string name="html"><![CDATA[<b>Bold</b> &amp; <i>italic</i>]]></string>
+
+    <string name="mixed">Hello, <![CDATA[<b>%s</b>]]>!</string>
+
+    <string name="space"><![CDATA[ ]]></string>
+</resources>
+`
+	if w.String() != expected {
+		t.Errorf("got: %s, want %s", w.String(), expected)
+	}
 }
 
 func TestComment(t *testing.T) {
-	p := New(indent)
+	p := New(DefaultOptions())
 
 	{
 		w := &strings.Builder{}
-		ee := []parse.Element{
-			{
-				Token:            xml.Comment("a comment"),
-				Depth:            0,
-				IsSelfClosing:    false,
-				ContainsCharData: false,
-			},
-		}
 
-		err := p.Fprint(w, ee)
+		err := p.printNode(w, comment("a comment"), 0)
 		requireNoError(t, err)
 
 		expected := "<!--a comment-->\n"
@@ -291,16 +703,8 @@ func TestComment(t *testing.T) {
 
 	{
 		w := &strings.Builder{}
-		ee := []parse.Element{
-			{
-				Token:            xml.Comment("a comment"),
-				Depth:            1,
-				IsSelfClosing:    false,
-				ContainsCharData: false,
-			},
-		}
 
-		err := p.Fprint(w, ee)
+		err := p.printNode(w, comment("a comment"), 1)
 		requireNoError(t, err)
 
 		expected := indent + "<!--a comment-->\n"
@@ -311,22 +715,16 @@ func TestComment(t *testing.T) {
 }
 
 func TestProcInst(t *testing.T) {
-	p := New(indent)
+	p := New(DefaultOptions())
 
 	w := &strings.Builder{}
-	ee := []parse.Element{
-		{
-			Token: xml.ProcInst{
-				Target: "xml",
-				Inst:   []byte(`version="1.0" encoding="utf-8"`),
-			},
-			Depth:            0,
-			IsSelfClosing:    false,
-			ContainsCharData: false,
-		},
+	n := &parse.Node{
+		Type:   parse.ProcInstNode,
+		Target: "xml",
+		Data:   `version="1.0" encoding="utf-8"`,
 	}
 
-	err := p.Fprint(w, ee)
+	err := p.Fprint(w, document(n))
 	requireNoError(t, err)
 
 	expected := `<?xml version="1.0" encoding="utf-8"?>` + "\n"
@@ -335,8 +733,96 @@ func TestProcInst(t *testing.T) {
 	}
 }
 
+func TestLineEnding(t *testing.T) {
+	opts := DefaultOptions()
+	opts.LineEnding = "\r\n"
+	opts.FinalNewline = false
+	p := New(opts)
+
+	w := &strings.Builder{}
+	root := document(element(xml.Name{Space: "", Local: "resources"}, nil, comment(" empty ")))
+
+	err := p.Fprint(w, root)
+	requireNoError(t, err)
+
+	expected := "<resources>\r\n\r\n" + indent + "<!-- empty -->\r\n</resources>"
+	if w.String() != expected {
+		t.Errorf("got: %q, want %q", w.String(), expected)
+	}
+}
+
 func requireNoError(t *testing.T, err error) {
 	if err != nil {
 		t.Errorf("unexpected error %v", err)
 	}
 }
+
+func TestIgnored(t *testing.T) {
+	doc := `<FrameLayout xmlns:android="http://schemas.android.com/apk/res/android" xmlns:card_view="http://schemas.android.com/apk/res-auto">
+  <!-- axmlfmt:off -->
+  <Button android:layout_width="wrap_content"   android:id="@+id/a"
+          card_view:layout_constraintStart_toStartOf="parent"/>
+  <!-- axmlfmt:on -->
+  <View android:layout_width="1dp" android:id="@+id/b"/>
+  <!-- axmlfmt:ignore-next -->
+  <View android:layout_width="1dp" android:id="@+id/c"/>
+</FrameLayout>`
+
+	root, err := parse.ReadXML([]byte(doc))
+	requireNoError(t, err)
+
+	w := &strings.Builder{}
+	err = New(DefaultOptions()).Fprint(w, root)
+	requireNoError(t, err)
+
+	// The prefix of the namespace used in the verbatim source is kept
+	expected := `<FrameLayout
+    xmlns:android="http://schemas.android.com/apk/res/android"
+    xmlns:card_view="http://schemas.android.com/apk/res-auto">
+
+    <!-- axmlfmt:off -->
+    <Button android:layout_width="wrap_content"   android:id="@+id/a"
+          card_view:layout_constraintStart_toStartOf="parent"/>
+
+    <!-- axmlfmt:on -->
+    <View
+        android:id="@+id/b"
+        android:layout_width="1dp" />
+
+    <!-- axmlfmt:ignore-next -->
+    <View android:layout_width="1dp" android:id="@+id/c"/>
+</FrameLayout>
+`
+	if w.String() != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", w.String(), expected)
+	}
+}
+
+// document returns the root of a tree with the given top-level nodes
+func document(children ...*parse.Node) *parse.Node {
+	n := &parse.Node{Type: parse.DocumentNode}
+	for _, c := range children {
+		n.AppendChild(c)
+	}
+	return n
+}
+
+// element returns an element node with the given attributes and children
+func element(name xml.Name, attrs []xml.Attr, children ...*parse.Node) *parse.Node {
+	n := &parse.Node{Type: parse.ElementNode, Name: name}
+	for _, a := range attrs {
+		n.Attrs = append(n.Attrs, parse.Attr{Attr: a})
+	}
+	for _, c := range children {
+		n.AppendChild(c)
+	}
+	return n
+}
+
+func text(data string) *parse.Node {
+	return &parse.Node{Type: parse.TextNode, Data: data}
+}
+
+func comment(data string) *parse.Node {
+	return &parse.Node{Type: parse.CommentNode, Data: data}
+}
@@ -1,8 +1,12 @@
 package printer
 
 import (
-	"encoding/xml"
+	"fmt"
+	"regexp"
 	"sort"
+	"strings"
+
+	"github.com/rsookram/axmlfmt/internal/parse"
 )
 
 var nsPriority = []string{
@@ -19,8 +23,186 @@ var androidPriority = []string{
 	"layout_height",
 }
 
-// sortAttrs returns a sorted slice of the given attributes in the following
-// order:
+// AnyNamespace is the namespace of an AttrRule which matches attributes in
+// any namespace
+const AnyNamespace = "*"
+
+// AttrRule matches attributes by namespace and local name. Attributes are
+// ordered by the first rule which matches them.
+type AttrRule struct {
+	// Namespace is the namespace URI of the attributes, "xmlns" for
+	// namespace declarations, "" for attributes without a namespace, or
+	// AnyNamespace
+	Namespace string
+
+	// Name matches the whole local name of the attributes
+	Name *regexp.Regexp
+}
+
+// ParseAttrRule returns the rule described by s, which is written as
+// `prefix:name`, or just `name` for attributes without a namespace. The
+// prefix is "xmlns" for namespace declarations, "*" for any namespace, or a
+// prefix from namespaces, which maps prefixes to URIs, or one of the
+// well-known prefixes such as android, app and tools. The name is a glob,
+// where `*` matches any characters and `?` matches one, or a regular
+// expression between slashes like `/layout_(width|height)/`.
+func ParseAttrRule(s string, namespaces map[string]string) (AttrRule, error) {
+	prefix, name := splitRule(s)
+
+	ns, err := ruleNamespace(prefix, namespaces)
+	if err != nil {
+		return AttrRule{}, fmt.Errorf("invalid attribute rule %q: %v", s, err)
+	}
+
+	if name == "" {
+		return AttrRule{}, fmt.Errorf("invalid attribute rule %q: missing name", s)
+	}
+
+	var pattern string
+	if len(name) > 1 && strings.HasPrefix(name, "/") && strings.HasSuffix(name, "/") {
+		pattern = name[1 : len(name)-1]
+	} else {
+		pattern = globPattern(name)
+	}
+
+	// The name has to match as a whole
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return AttrRule{}, fmt.Errorf("invalid attribute rule %q: %v", s, err)
+	}
+
+	return AttrRule{Namespace: ns, Name: re}, nil
+}
+
+// ParseAttrRules parses each of the given rules with ParseAttrRule
+func ParseAttrRules(rules []string, namespaces map[string]string) ([]AttrRule, error) {
+	parsed := make([]AttrRule, len(rules))
+	for i, s := range rules {
+		rule, err := ParseAttrRule(s, namespaces)
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = rule
+	}
+
+	return parsed, nil
+}
+
+// SplitAttrRules splits a comma-separated list of rules. Commas in regular
+// expressions, like the one in `android:/layout_margin.{0,6}/`, don't
+// separate rules.
+func SplitAttrRules(s string) []string {
+	rules := make([]string, 0)
+	for _, part := range strings.Split(s, ",") {
+		if n := len(rules); n > 0 && isOpenRegexp(rules[n-1]) {
+			rules[n-1] += "," + part
+			continue
+		}
+
+		rules = append(rules, part)
+	}
+
+	return rules
+}
+
+// isOpenRegexp returns whether the name of the rule s is the start of a
+// regular expression which hasn't been closed by a slash yet
+func isOpenRegexp(s string) bool {
+	_, name := splitRule(s)
+	return strings.HasPrefix(name, "/") && (len(name) == 1 || !strings.HasSuffix(name, "/"))
+}
+
+// splitRule returns the prefix and name of the rule s
+func splitRule(s string) (string, string) {
+	if i := strings.Index(s, ":"); i >= 0 && !strings.HasPrefix(s, "/") {
+		return s[:i], s[i+1:]
+	}
+
+	return "", s
+}
+
+// ruleNamespace returns the namespace URI which prefix refers to in a rule
+func ruleNamespace(prefix string, namespaces map[string]string) (string, error) {
+	switch prefix {
+	case "", "xmlns", AnyNamespace:
+		return prefix, nil
+	}
+
+	if ns, ok := namespaces[prefix]; ok {
+		return ns, nil
+	}
+
+	if ns, ok := WellKnownNamespace(prefix); ok {
+		return ns, nil
+	}
+
+	return "", fmt.Errorf("unknown namespace prefix %q", prefix)
+}
+
+// globPattern returns a regular expression which matches the same names as
+// the glob
+func globPattern(glob string) string {
+	b := &strings.Builder{}
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	return b.String()
+}
+
+func (r AttrRule) matches(a parse.Attr) bool {
+	if r.Namespace != AnyNamespace && r.Namespace != a.Name.Space {
+		return false
+	}
+
+	return r.Name.MatchString(a.Name.Local)
+}
+
+// attrOrder holds the rules which determine the order of attributes
+type attrOrder struct {
+	rules []AttrRule
+}
+
+// orderFromPriorities returns the order which puts attributes in the order of
+// their namespace URI, with the given android attributes first in the android
+// namespace and xmlns:android first among the namespace declarations
+func orderFromPriorities(namespaces, android []string) attrOrder {
+	rules := make([]AttrRule, 0, len(namespaces)+len(android)+1)
+
+	for _, ns := range namespaces {
+		switch ns {
+		case "xmlns":
+			rules = append(rules, literalRule(ns, "android"))
+		case "http://schemas.android.com/apk/res/android":
+			for _, name := range android {
+				rules = append(rules, literalRule(ns, name))
+			}
+		}
+
+		rules = append(rules, AttrRule{Namespace: ns, Name: anyName})
+	}
+
+	return attrOrder{rules: rules}
+}
+
+var anyName = regexp.MustCompile(`^.*$`)
+
+func literalRule(ns, name string) AttrRule {
+	return AttrRule{Namespace: ns, Name: regexp.MustCompile("^" + regexp.QuoteMeta(name) + "$")}
+}
+
+var defaultAttrOrder = orderFromPriorities(nsPriority, androidPriority)
+
+// sortAttrs returns a sorted slice of the given attributes of an element with
+// the given namespaces in scope. With the default order, they're sorted as
+// follows:
 //
 //   - xmlns:android
 //   - xmlns:* (alphabetic)
@@ -31,64 +213,49 @@ var androidPriority = []string{
 //   - app:* (alphabetic)
 //   - tools:* (alphabetic)
 //   - :* (alphabetic)
-func sortAttrs(attrs []xml.Attr) []xml.Attr {
-	sorted := make([]xml.Attr, len(attrs))
-	copy(sorted, attrs)
-
-	sort.Slice(sorted, func(i, j int) bool {
-		return less(sorted[i].Name, sorted[j].Name)
-	})
-
-	return sorted
-}
-
-func less(fst, snd xml.Name) bool {
-	if fst.Space != snd.Space {
-		fstP, hasFst := indexOf(nsPriority, fst.Space)
-		sndP, hasSnd := indexOf(nsPriority, snd.Space)
-
-		if hasFst && hasSnd {
-			return fstP < sndP
-		}
-		if hasFst {
-			return true
-		}
-		if hasSnd {
-			return false
-		}
+//
+// Attributes matching the same rule are sorted by their name as it's
+// printed, including the prefix. Attributes which no rule matches come last,
+// sorted by local name.
+func (p Printer) sortAttrs(attrs []parse.Attr, scope map[string]string) []parse.Attr {
+	ranks := make([]int, len(attrs))
+	names := make([]string, len(attrs))
+	indices := make([]int, len(attrs))
+	for i, a := range attrs {
+		ranks[i] = p.attrOrder.rank(a)
+		names[i] = p.cleanAttrName(a.Attr, scope)
+		indices[i] = i
 	}
+	unmatched := len(p.attrOrder.rules)
 
-	ns := fst.Space
-	switch ns {
-	case "xmlns":
-		// "android" is always first
-		if fst.Local == "android" && snd.Local != "android" {
-			return true
+	sort.Slice(indices, func(i, j int) bool {
+		fst, snd := indices[i], indices[j]
+		if ranks[fst] != ranks[snd] {
+			return ranks[fst] < ranks[snd]
 		}
-		return fst.Local < snd.Local
-	case "http://schemas.android.com/apk/res/android":
-		fstP, hasFst := indexOf(androidPriority, fst.Local)
-		sndP, hasSnd := indexOf(androidPriority, snd.Local)
 
-		if hasFst && hasSnd {
-			return fstP < sndP
-		}
-		if hasFst {
-			return true
-		}
-		if hasSnd {
-			return false
+		if ranks[fst] == unmatched && attrs[fst].Name.Local != attrs[snd].Name.Local {
+			return attrs[fst].Name.Local < attrs[snd].Name.Local
 		}
+		return names[fst] < names[snd]
+	})
+
+	sorted := make([]parse.Attr, len(attrs))
+	for i, idx := range indices {
+		sorted[i] = attrs[idx]
 	}
-	return fst.Local < snd.Local
+
+	return sorted
 }
 
-func indexOf(haystack []string, needle string) (int, bool) {
-	for i, s := range haystack {
-		if s == needle {
-			return i, true
+// rank returns the position of the first rule which matches the attribute,
+// or the number of rules if none do
+func (o attrOrder) rank(a parse.Attr) int {
+	for i, r := range o.rules {
+		if r.matches(a) {
+			return i
 		}
 	}
 
-	return -1, false
+	return len(o.rules)
 }
@@ -3,8 +3,11 @@ package printer
 import (
 	"encoding/xml"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/rsookram/axmlfmt/internal/parse"
 )
 
 const androidNS = "http://schemas.android.com/apk/res/android"
@@ -12,16 +15,16 @@ const appNS = "http://schemas.android.com/apk/res-auto"
 const toolsNS = "http://schemas.android.com/tools"
 
 func TestNamespace(t *testing.T) {
-	attrs := []xml.Attr{
+	attrs := []parse.Attr{
 		attr("xmlns", "unknown", "http://schemas.android.com/apk/custom"),
 		attr("xmlns", "app", appNS),
 		attr("xmlns", "tools", toolsNS),
 		attr("xmlns", "android", androidNS),
 	}
 
-	sorted := sortAttrs(attrs)
+	sorted := New(DefaultOptions()).sortAttrs(attrs, nil)
 
-	expected := []xml.Attr{
+	expected := []parse.Attr{
 		attr("xmlns", "android", androidNS),
 		attr("xmlns", "app", appNS),
 		attr("xmlns", "tools", toolsNS),
@@ -33,7 +36,7 @@ func TestNamespace(t *testing.T) {
 }
 
 func TestAndroidAttrs(t *testing.T) {
-	attrs := []xml.Attr{
+	attrs := []parse.Attr{
 		attr(androidNS, "layout_weight", "1"),
 		attr(androidNS, "layout_width", "wrap_content"),
 		attr(androidNS, "gravity", "center"),
@@ -41,9 +44,9 @@ func TestAndroidAttrs(t *testing.T) {
 		attr(androidNS, "layout_height", "match_parent"),
 	}
 
-	sorted := sortAttrs(attrs)
+	sorted := New(DefaultOptions()).sortAttrs(attrs, nil)
 
-	expected := []xml.Attr{
+	expected := []parse.Attr{
 		attr(androidNS, "id", "@+id/open"),
 		attr(androidNS, "layout_width", "wrap_content"),
 		attr(androidNS, "layout_height", "match_parent"),
@@ -56,15 +59,15 @@ func TestAndroidAttrs(t *testing.T) {
 }
 
 func TestAppAttrs(t *testing.T) {
-	attrs := []xml.Attr{
+	attrs := []parse.Attr{
 		attr(appNS, "layout_constraintTop_toTopOf", "parent"),
 		attr(appNS, "layoutManager", "androidx.recyclerview.widget.LinearLayoutManager"),
 		attr(appNS, "layout_constraintBottom_toBottomOf", "@id/title"),
 	}
 
-	sorted := sortAttrs(attrs)
+	sorted := New(DefaultOptions()).sortAttrs(attrs, nil)
 
-	expected := []xml.Attr{
+	expected := []parse.Attr{
 		attr(appNS, "layoutManager", "androidx.recyclerview.widget.LinearLayoutManager"),
 		attr(appNS, "layout_constraintBottom_toBottomOf", "@id/title"),
 		attr(appNS, "layout_constraintTop_toTopOf", "parent"),
@@ -75,7 +78,7 @@ func TestAppAttrs(t *testing.T) {
 }
 
 func TestNamespaceComparison(t *testing.T) {
-	attrs := []xml.Attr{
+	attrs := []parse.Attr{
 		attr("xmlns", "app", appNS),
 		attr(androidNS, "id", "@+id/open"),
 		attr(androidNS, "layout_width", "wrap_content"),
@@ -87,9 +90,9 @@ func TestNamespaceComparison(t *testing.T) {
 		attr(appNS, "layoutManager", "androidx.recyclerview.widget.LinearLayoutManager"),
 	}
 
-	sorted := sortAttrs(attrs)
+	sorted := New(DefaultOptions()).sortAttrs(attrs, nil)
 
-	expected := []xml.Attr{
+	expected := []parse.Attr{
 		attr("xmlns", "android", androidNS),
 		attr("xmlns", "app", appNS),
 		attr("xmlns", "tools", toolsNS),
@@ -105,15 +108,189 @@ func TestNamespaceComparison(t *testing.T) {
 	}
 }
 
-func attr(space, local, value string) xml.Attr {
-	return xml.Attr{Name: tagName(space, local), Value: value}
+func TestNamespaceDeclarationsBeforeAndroid(t *testing.T) {
+	attrs := []parse.Attr{
+		attr("xmlns", "aapt", "http://schemas.android.com/aapt"),
+		attr("xmlns", "android", androidNS),
+	}
+
+	sorted := New(DefaultOptions()).sortAttrs(attrs, nil)
+
+	expected := []parse.Attr{
+		attr("xmlns", "android", androidNS),
+		attr("xmlns", "aapt", "http://schemas.android.com/aapt"),
+	}
+	if str(sorted) != str(expected) {
+		t.Errorf("got\n%s\n, want\n%s", str(sorted), str(expected))
+	}
+}
+
+func TestAttrRules(t *testing.T) {
+	rules, err := ParseAttrRules([]string{
+		"xmlns:*",
+		"style",
+		"android:id",
+		"android:layout_margin*",
+		"android:padding*",
+		"android:*",
+		"app:/layout_constraint(Start|End)_.*/",
+		"app:layout_constraint*",
+		"*:*",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := []parse.Attr{
+		attr(appNS, "layout_constraintTop_toTopOf", "parent"),
+		attr(androidNS, "paddingTop", "4dp"),
+		attr(appNS, "layout_constraintStart_toStartOf", "parent"),
+		attr(androidNS, "layout_marginTop", "8dp"),
+		attr(toolsNS, "text", "x"),
+		attr(androidNS, "gravity", "center"),
+		attr("", "style", "@style/list"),
+		attr(androidNS, "id", "@+id/open"),
+		attr("xmlns", "app", appNS),
+		attr("", "layout", "@layout/item"),
+	}
+
+	sorted := New(Options{AttributeOrder: rules}).sortAttrs(attrs, nil)
+
+	expected := []parse.Attr{
+		attr("xmlns", "app", appNS),
+		attr("", "style", "@style/list"),
+		attr(androidNS, "id", "@+id/open"),
+		attr(androidNS, "layout_marginTop", "8dp"),
+		attr(androidNS, "paddingTop", "4dp"),
+		attr(androidNS, "gravity", "center"),
+		attr(appNS, "layout_constraintStart_toStartOf", "parent"),
+		attr(appNS, "layout_constraintTop_toTopOf", "parent"),
+		attr("", "layout", "@layout/item"),
+		attr(toolsNS, "text", "x"),
+	}
+	if str(sorted) != str(expected) {
+		t.Errorf("got\n%s\n, want\n%s", str(sorted), str(expected))
+	}
+}
+
+func TestParseAttrRule(t *testing.T) {
+	tests := []struct {
+		rule     string
+		ns       string
+		match    string
+		nonMatch string
+	}{
+		{"style", "", "style", "styles"},
+		{"xmlns:*", "xmlns", "tools", ""},
+		{"*:text", AnyNamespace, "text", "textSize"},
+		{"bind:visible?", "http://example.com/bind", "visible1", "visible"},
+		{"android:/layout_(width|height)/", androidNS, "layout_width", "layout_widthx"},
+		{"/a:b/", "", "a:b", "b"},
+	}
+
+	for _, tt := range tests {
+		rule, err := ParseAttrRule(tt.rule, map[string]string{"bind": "http://example.com/bind"})
+		if err != nil {
+			t.Errorf("got %v for %q", err, tt.rule)
+			continue
+		}
+
+		if rule.Namespace != tt.ns {
+			t.Errorf("got namespace %q for %q, want %q", rule.Namespace, tt.rule, tt.ns)
+		}
+		if !rule.Name.MatchString(tt.match) {
+			t.Errorf("got %s for %q, want a pattern matching %q", rule.Name, tt.rule, tt.match)
+		}
+		if tt.nonMatch != "" && rule.Name.MatchString(tt.nonMatch) {
+			t.Errorf("got %s for %q, want a pattern which doesn't match %q", rule.Name, tt.rule, tt.nonMatch)
+		}
+	}
+
+	for _, rule := range []string{"custom:name", "android:", "android:/(/", `android:/\Qfoo/`} {
+		_, err := ParseAttrRule(rule, nil)
+		if err == nil {
+			t.Errorf("got no error for %q", rule)
+		}
+	}
+}
+
+func TestSplitAttrRules(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+	}{
+		{"xmlns:*,style,*", []string{"xmlns:*", "style", "*"}},
+		{"android:/layout_margin.{0,6}/,*", []string{"android:/layout_margin.{0,6}/", "*"}},
+		{"/a{1,2}/,/b,c/", []string{"/a{1,2}/", "/b,c/"}},
+		{"android:/x,", []string{"android:/x,"}},
+	}
+
+	for _, tt := range tests {
+		got := SplitAttrRules(tt.s)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("got %q for %q, want %q", got, tt.s, tt.want)
+		}
+	}
+}
+
+func TestAndroidStudioPreset(t *testing.T) {
+	rules, err := AttrOrderPreset("android-studio")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := []parse.Attr{
+		attr(toolsNS, "text", "x"),
+		attr(androidNS, "paddingTop", "4dp"),
+		attr(appNS, "layout_constraintTop_toTopOf", "parent"),
+		attr(androidNS, "layout_weight", "1"),
+		attr(androidNS, "layout_height", "match_parent"),
+		attr("", "style", "@style/list"),
+		attr(androidNS, "layout_width", "wrap_content"),
+		attr("", "class", "Item"),
+		attr(androidNS, "name", "Item"),
+		attr(androidNS, "id", "@+id/open"),
+		attr("xmlns", "tools", toolsNS),
+		attr("xmlns", "android", androidNS),
+		attr("", "name", "item"),
+	}
+
+	sorted := New(Options{AttributeOrder: rules}).sortAttrs(attrs, nil)
+
+	expected := []parse.Attr{
+		attr("xmlns", "android", androidNS),
+		attr("xmlns", "tools", toolsNS),
+		attr(androidNS, "id", "@+id/open"),
+		attr(androidNS, "name", "Item"),
+		attr("", "name", "item"),
+		attr("", "style", "@style/list"),
+		attr("", "class", "Item"),
+		attr(androidNS, "layout_width", "wrap_content"),
+		attr(androidNS, "layout_height", "match_parent"),
+		attr(androidNS, "layout_weight", "1"),
+		attr(androidNS, "paddingTop", "4dp"),
+		attr(appNS, "layout_constraintTop_toTopOf", "parent"),
+		attr(toolsNS, "text", "x"),
+	}
+	if str(sorted) != str(expected) {
+		t.Errorf("got\n%s\n, want\n%s", str(sorted), str(expected))
+	}
+
+	_, err = AttrOrderPreset("eclipse")
+	if err == nil {
+		t.Errorf("got no error for an unknown preset")
+	}
+}
+
+func attr(space, local, value string) parse.Attr {
+	return parse.Attr{Attr: xml.Attr{Name: tagName(space, local), Value: value}}
 }
 
 func tagName(space, local string) xml.Name {
 	return xml.Name{Space: space, Local: local}
 }
 
-func str(attrs []xml.Attr) string {
+func str(attrs []parse.Attr) string {
 	strs := []string{}
 
 	for _, a := range attrs {
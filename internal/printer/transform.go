@@ -0,0 +1,171 @@
+package printer
+
+import (
+	"strings"
+
+	"github.com/rsookram/axmlfmt/internal/parse"
+)
+
+// arrange applies the rules for the order of attributes and the blank lines
+// between nodes to the tree with the given root
+func (p Printer) arrange(root *parse.Node) {
+	p.sortAttributes(root)
+	p.assignBlankLines(root)
+}
+
+// sortAttributes sorts the attributes of each element in the tree
+func (p Printer) sortAttributes(n *parse.Node) {
+	if n.Type == parse.ElementNode {
+		n.Attrs = p.sortAttrs(n.Attrs, n.Namespaces)
+	}
+
+	for _, c := range n.Children {
+		p.sortAttributes(c)
+	}
+}
+
+// assignBlankLines sets the number of blank lines before each node in the
+// tree according to the blank line policy
+func (p Printer) assignBlankLines(n *parse.Node) {
+	var prev *parse.Node
+	for _, c := range n.Children {
+		c.BlankLinesBefore = p.blankLinesBefore(c, prev)
+		prev = c
+
+		p.assignBlankLines(c)
+	}
+}
+
+// blankLinesBefore returns the number of blank lines to print before n,
+// whose previous sibling is prev. Blank lines only come before elements,
+// comments and verbatim nodes which aren't inline, and verbatim nodes are
+// treated like elements. BlankLinesAlways separates them from a
+// preceding element, or the start of their parent, while BlankLinesPreserve
+// also keeps blank lines after comments, processing instructions and
+// directives.
+func (p Printer) blankLinesBefore(n, prev *parse.Node) int {
+	if p.blankLines == BlankLinesNever || n.IsInline() {
+		return 0
+	}
+
+	switch n.Type {
+	case parse.ElementNode, parse.CommentNode, parse.VerbatimNode:
+	default:
+		return 0
+	}
+
+	if prev == nil && n.Parent.Type == parse.DocumentNode {
+		// Nothing comes before the first node of the document
+		return 0
+	}
+
+	if prev == nil || prev.Type == parse.ElementNode || prev.Type == parse.VerbatimNode {
+		if p.blankLines == BlankLinesAlways {
+			return 1
+		}
+		return p.preservedBlankLines(n)
+	}
+
+	switch prev.Type {
+	case parse.CommentNode, parse.ProcInstNode, parse.DirectiveNode:
+		if p.blankLines == BlankLinesPreserve {
+			return p.preservedBlankLines(n)
+		}
+	}
+
+	return 0
+}
+
+// preservedBlankLines returns the number of blank lines to keep before the
+// given node with BlankLinesPreserve
+func (p Printer) preservedBlankLines(n *parse.Node) int {
+	blank := n.NewLinesBefore - 1
+	if blank > p.maxBlank {
+		blank = p.maxBlank
+	}
+	if blank < 0 {
+		blank = 0
+	}
+
+	return blank
+}
+
+// keepVerbatimPrefixes returns a printer which doesn't standardize the
+// prefixes of the namespaces used in verbatim nodes in the tree, since their
+// source can't be changed to use the standard prefix
+func (p Printer) keepVerbatimPrefixes(root *parse.Node) Printer {
+	used := make(map[string]bool)
+	verbatimNamespaces(root, used)
+
+	return p.keepPrefixes(used)
+}
+
+// keepShadowedPrefixes returns a printer which doesn't standardize the
+// prefixes of namespaces whose standard prefix is bound to a different
+// namespace anywhere in the tree. A declaration renamed to the standard
+// prefix would leave the declared prefix undeclared where it's used below
+// that binding.
+func (p Printer) keepShadowedPrefixes(root *parse.Node) Printer {
+	bound := make(map[string]map[string]bool)
+	boundNamespaces(root, bound)
+
+	shadowed := make(map[string]bool)
+	for ns, prefix := range p.prefixes {
+		for uri := range bound[prefix] {
+			if uri != ns {
+				shadowed[ns] = true
+			}
+		}
+	}
+
+	return p.keepPrefixes(shadowed)
+}
+
+// keepPrefixes returns a printer which doesn't standardize the prefixes of
+// the given namespaces
+func (p Printer) keepPrefixes(namespaces map[string]bool) Printer {
+	if len(namespaces) == 0 {
+		return p
+	}
+
+	prefixes := make(map[string]string, len(p.prefixes))
+	for ns, prefix := range p.prefixes {
+		if !namespaces[ns] {
+			prefixes[ns] = prefix
+		}
+	}
+	p.prefixes = prefixes
+
+	return p
+}
+
+// boundNamespaces adds the URIs which each prefix is bound to in the tree to
+// bound
+func boundNamespaces(n *parse.Node, bound map[string]map[string]bool) {
+	for prefix, ns := range n.Namespaces {
+		if bound[prefix] == nil {
+			bound[prefix] = make(map[string]bool)
+		}
+		bound[prefix][ns] = true
+	}
+
+	for _, c := range n.Children {
+		boundNamespaces(c, bound)
+	}
+}
+
+// verbatimNamespaces adds the URIs of the namespaces whose prefixes appear in
+// the source of verbatim nodes in the tree to used
+func verbatimNamespaces(n *parse.Node, used map[string]bool) {
+	if n.Type == parse.VerbatimNode {
+		for prefix, ns := range n.Namespaces {
+			if prefix != "" && strings.Contains(n.Data, prefix+":") {
+				used[ns] = true
+			}
+		}
+	}
+
+	for _, c := range n.Children {
+		verbatimNamespaces(c, used)
+	}
+}